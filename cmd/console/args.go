@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Kankeran/console"
+)
+
+const (
+	completeCommand = "__complete"
+	profilesCommand = "profiles"
+)
+
+type options struct {
+	address    string
+	inputFile  string
+	assumeYes  bool
+	logLevel   console.LogLevel
+	output     string
+	completion string
+	profile    string
+	targets    string
+	parallel   int
+
+	// flagArgs are the arguments after the name of the command, parsed
+	// again once the server described the flags of the command
+	flagArgs []string
+}
+
+func parseArgs(args []string) (options, console.CommandMessage, error) {
+	msg := console.CommandMessage{Flags: make(map[string][]string)}
+	opts, args, err := parseOptions(args)
+	if err != nil {
+		return opts, msg, err
+	}
+
+	// "console __complete <words...>" is run by the completion script with
+	// the words typed so far, the server answers with the candidates.
+	if len(args) > 0 && args[0] == completeCommand {
+		msg.Name = completeCommand
+		msg.Flags["args"] = args[1:]
+		return opts, msg, nil
+	}
+
+	// "cache flush --region eu" runs the command "cache flush", running
+	// no command at all lists the commands of the server.
+	var path []string
+	for len(args) > 0 && !isFlag(args[0]) {
+		path, args = append(path, args[0]), args[1:]
+	}
+	if len(path) == 0 {
+		path = []string{"help"}
+	}
+	msg.Name = strings.Join(path, " ")
+	opts.flagArgs = args
+	msg.Flags, err = parseFlags(args, nil)
+
+	return opts, msg, err
+}
+
+// parseFlags reads "--name=value", "--name value", "-n=value" or
+// "-n value" flags. With the description of the command the argument after
+// a flag which is not a bool is its value, even when it starts with a dash
+// like "--since -2h", unless it is a declared flag itself, and undeclared
+// flags are rejected. Without it an
+// argument starting with a dash is a flag, unless it is a number, so
+// such values have to be given as --name=value.
+func parseFlags(args []string, d *console.CommandDescription) (map[string][]string, error) {
+	flags := make(map[string][]string)
+	for len(args) > 0 {
+		if !isFlag(args[0]) {
+			return flags, fmt.Errorf("unexpected argument %q", args[0])
+		}
+		name, value, rest, bare := splitFlag(args)
+		if d != nil && !strings.Contains(args[0], "=") {
+			takesValue, declared := flagTakesValue(d, name)
+			if !declared {
+				return flags, fmt.Errorf("unknown flag %s for %q", args[0], d.Name)
+			}
+			switch {
+			case takesValue && len(args) > 1 && !isDeclaredFlag(d, args[1]):
+				value, rest, bare = args[1], args[2:], false
+			default:
+				value, rest, bare = "", args[1:], true
+			}
+		}
+		args = rest
+		if bare {
+			// sent without values, the server decides what a bare flag
+			// means, e.g. true for --verbose
+			if _, ok := flags[name]; !ok {
+				flags[name] = nil
+			}
+			continue
+		}
+		flags[name] = append(flags[name], value)
+	}
+	return flags, nil
+}
+
+// flagTakesValue reports whether the flag named name, or a short name, of
+// d takes the next argument as its value and whether d declares it.
+func flagTakesValue(d *console.CommandDescription, name string) (takesValue, declared bool) {
+	if name == "help" {
+		return false, true
+	}
+	f, ok := d.Flag(name)
+	if !ok {
+		// --no-name negates a bool flag
+		negated, isNegated := strings.CutPrefix(name, "no-")
+		if f, ok = d.Flag(negated); !isNegated || !ok || !isBoolType(f.Type) {
+			return false, false
+		}
+	}
+	return !isBoolType(f.Type), true
+}
+
+// isDeclaredFlag reports whether arg is a flag of d, like "--user",
+// "--user=bob" or "-u", rather than a value starting with a dash.
+func isDeclaredFlag(d *console.CommandDescription, arg string) bool {
+	if !isFlag(arg) {
+		return false
+	}
+	name, _, _, _ := splitFlag([]string{arg})
+	_, declared := flagTakesValue(d, name)
+	return declared
+}
+
+func isBoolType(t string) bool {
+	return t == "bool" || t == "[]bool"
+}
+
+// parseOptions reads the options of the client, which come before the
+// name of the command.
+func parseOptions(args []string) (options, []string, error) {
+	opts := options{logLevel: console.LevelInfo, output: "text"}
+
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		if args[0] == "--yes" {
+			opts.assumeYes = true
+			args = args[1:]
+			continue
+		}
+
+		name, value, rest, bare := splitFlag(args)
+		if bare {
+			return opts, args, fmt.Errorf("option %s needs a value", args[0])
+		}
+		args = rest
+
+		switch name {
+		case "address":
+			opts.address = value
+		case "input-file":
+			opts.inputFile = value
+		case "output":
+			if err := checkOutputFormat(value); err != nil {
+				return opts, args, err
+			}
+			opts.output = value
+		case "log-level":
+			level, err := console.ParseLogLevel(value)
+			if err != nil {
+				return opts, args, err
+			}
+			opts.logLevel = level
+		case "completion":
+			opts.completion = value
+		case "profile":
+			opts.profile = value
+		case "targets":
+			opts.targets = value
+		case "parallel":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return opts, args, fmt.Errorf("option --parallel needs a positive number, got %q", value)
+			}
+			opts.parallel = n
+		default:
+			return opts, args, fmt.Errorf("unknown option --%s", name)
+		}
+	}
+
+	return opts, args, nil
+}
+
+// isFlag reports whether arg is a flag rather than a value, negative
+// numbers are values.
+func isFlag(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	_, err := strconv.ParseFloat(arg, 64)
+	return err != nil
+}
+
+// splitFlag reads "--name=value", "--name value", "-n=value" or "-n value"
+// from the head of args. A flag followed by another flag or by nothing is
+// bare.
+func splitFlag(args []string) (name, value string, rest []string, bare bool) {
+	name = strings.TrimPrefix(strings.TrimPrefix(args[0], "-"), "-")
+	if i := strings.IndexByte(name, '='); i >= 0 {
+		return name[:i], name[i+1:], args[1:], false
+	}
+	if len(args) < 2 || isFlag(args[1]) {
+		return name, "", args[1:], true
+	}
+	return name, args[1], args[2:], false
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Kankeran/console"
+)
+
+func TestParseFlags(t *testing.T) {
+	d := console.CommandDescription{
+		Name: "logs",
+		Flags: []console.FlagDescription{
+			{Name: "since", Type: "time.Duration"},
+			{Name: "offset", Short: "o", Type: "int"},
+			{Name: "follow", Short: "f", Type: "bool"},
+			{Name: "tag", Type: "[]string"},
+			{Name: "password", Short: "p", Type: "string", Secret: true},
+			{Name: "user", Short: "u", Type: "string"},
+		},
+	}
+	tests := []struct {
+		args     []string
+		d        *console.CommandDescription
+		expected map[string][]string
+		err      bool
+	}{
+		{[]string{"--since", "-2h"}, &d, map[string][]string{"since": {"-2h"}}, false},
+		{[]string{"-o", "-5", "-f"}, &d, map[string][]string{"o": {"-5"}, "f": nil}, false},
+		{[]string{"--follow", "--tag", "--x"}, &d, map[string][]string{"follow": nil, "tag": {"--x"}}, false},
+		{[]string{"--no-follow", "--since=1h", "--help"}, &d, map[string][]string{"no-follow": nil, "since": {"1h"}, "help": nil}, false},
+		{[]string{"--follow=false"}, &d, map[string][]string{"follow": {"false"}}, false},
+		{[]string{"--since"}, &d, map[string][]string{"since": nil}, false},
+		{[]string{"--password", "--user", "bob"}, &d, map[string][]string{"password": nil, "user": {"bob"}}, false},
+		{[]string{"-u", "bob", "-p", "-f"}, &d, map[string][]string{"u": {"bob"}, "p": nil, "f": nil}, false},
+		{[]string{"--password", "--user=bob"}, &d, map[string][]string{"password": nil, "user": {"bob"}}, false},
+		{[]string{"--password", "--no-follow"}, &d, map[string][]string{"password": nil, "no-follow": nil}, false},
+		{[]string{"--password", "-x"}, &d, map[string][]string{"password": {"-x"}}, false},
+		{[]string{"--unknown", "x"}, &d, nil, true},
+		{[]string{"--no-since"}, &d, nil, true},
+		{[]string{"--follow", "x"}, &d, nil, true},
+		{[]string{"--since", "-2h"}, nil, map[string][]string{"since": nil, "2h": nil}, false},
+		{[]string{"--since=-2h", "--offset", "-5"}, nil, map[string][]string{"since": {"-2h"}, "offset": {"-5"}}, false},
+	}
+	for _, test := range tests {
+		flags, err := parseFlags(test.args, test.d)
+		if test.err {
+			if err == nil {
+				t.Errorf("parseFlags(%q) = %v; want an error", test.args, flags)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(flags, test.expected) {
+			t.Errorf("parseFlags(%q) = %v, %v; want %v", test.args, flags, err, test.expected)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+const bashCompletion = `_console() {
+	local IFS=$'\n'
+	COMPREPLY=($(console __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _console console
+`
+
+const zshCompletion = `#compdef console
+_console() {
+	local -a candidates
+	candidates=("${(@f)$(console __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	compadd -a candidates
+}
+compdef _console console
+`
+
+func writeCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		_, err := io.WriteString(w, bashCompletion)
+		return err
+	case "zsh":
+		_, err := io.WriteString(w, zshCompletion)
+		return err
+	}
+	return fmt.Errorf("no completion for shell %q, expected bash or zsh", shell)
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Kankeran/console"
+)
+
+// config is read from $CONSOLE_CONFIG, or from console/config.json in the
+// user config directory, e.g.
+//
+//	{
+//	  "address": "localhost:51005",
+//	  "defaults": {"region": "eu"},
+//	  "commands": {"cache flush": {"mode": "hard"}},
+//	  "default_profile": "prod",
+//	  "profiles": {
+//	    "prod": {
+//	      "address": "prod:51005",
+//	      "token_env": "PROD_CONSOLE_TOKEN",
+//	      "tls": {"ca": "/etc/console/ca.pem"},
+//	      "defaults": {"region": "us"}
+//	    }
+//	  },
+//	  "groups": {"caches": ["prod", "staging"]}
+//	}
+//
+// "defaults" apply to every command declaring the flag, "commands" to a
+// single command. Settings of the selected profile take precedence over
+// those at the top of the file. "groups" name profiles a command runs on
+// together with --targets.
+type config struct {
+	profile
+	DefaultProfile string              `json:"default_profile,omitempty"`
+	Profiles       map[string]profile  `json:"profiles,omitempty"`
+	Groups         map[string][]string `json:"groups,omitempty"`
+}
+
+type profile struct {
+	Address  string                  `json:"address,omitempty"`
+	Token    string                  `json:"token,omitempty"`
+	TokenEnv string                  `json:"token_env,omitempty"`
+	TLS      *tlsConfig              `json:"tls,omitempty"`
+	Defaults flagDefaults            `json:"defaults,omitempty"`
+	Commands map[string]flagDefaults `json:"commands,omitempty"`
+}
+
+type tlsConfig struct {
+	CA                 string `json:"ca,omitempty"`
+	Cert               string `json:"cert,omitempty"`
+	Key                string `json:"key,omitempty"`
+	ServerName         string `json:"server_name,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+func (t *tlsConfig) load() (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: t.ServerName, InsecureSkipVerify: t.InsecureSkipVerify}
+	if t.CA != "" {
+		pem, err := os.ReadFile(t.CA)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found", t.CA)
+		}
+	}
+	if t.Cert != "" || t.Key != "" {
+		cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+type flagDefaults map[string]flagValues
+
+// flagValues is a single value or a list of values.
+type flagValues []string
+
+func (v *flagValues) UnmarshalJSON(b []byte) error {
+	var list []any
+	if err := json.Unmarshal(b, &list); err != nil {
+		var value any
+		if err := json.Unmarshal(b, &value); err != nil {
+			return err
+		}
+		list = []any{value}
+	}
+	*v = make(flagValues, len(list))
+	for i, value := range list {
+		switch value.(type) {
+		case string, float64, bool:
+			(*v)[i] = fmt.Sprint(value)
+		default:
+			return fmt.Errorf("flag value %s is not a string, number or bool", b)
+		}
+	}
+	return nil
+}
+
+func configPath() (string, error) {
+	if path := os.Getenv("CONSOLE_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "console", "config.json"), nil
+}
+
+// loadConfig reads the config file, a missing file is an empty config.
+func loadConfig() (config, error) {
+	var c config
+	path, err := configPath()
+	if err != nil {
+		return c, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// selectedProfile returns the profile named by the --profile option, by
+// $CONSOLE_PROFILE or the default profile, in that order. Without profiles
+// it is empty.
+func (c config) selectedProfile(option string) (string, profile, error) {
+	name := option
+	for _, n := range []string{os.Getenv("CONSOLE_PROFILE"), c.DefaultProfile} {
+		if name == "" {
+			name = n
+		}
+	}
+	if name == "" {
+		return "", profile{}, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return name, profile{}, fmt.Errorf("unknown profile %q", name)
+	}
+	return name, p, nil
+}
+
+// address returns the address of the server: the --address option, then
+// the address of the profile, $CMD_ADDRESS, the address of the config file
+// and at last the default address. The profile comes before the
+// environment so that its token and TLS settings are not sent to a server
+// the profile does not name.
+func (c config) address(p profile, option string) string {
+	for _, address := range []string{option, p.Address, os.Getenv("CMD_ADDRESS"), c.Address} {
+		if address != "" {
+			return address
+		}
+	}
+	return console.GetAdress()
+}
+
+// token returns the token sent to the server, read from the config file
+// or from the environment variable it names.
+func (c config) token(p profile) string {
+	for _, src := range []profile{p, c.profile} {
+		if src.Token != "" {
+			return src.Token
+		}
+		if src.TokenEnv != "" {
+			return os.Getenv(src.TokenEnv)
+		}
+	}
+	return ""
+}
+
+// credentials returns the TLS settings and the token sent to t, none for
+// anonymous targets.
+func (c config) credentials(t target) (*tls.Config, string, error) {
+	if t.anonymous {
+		return nil, "", nil
+	}
+	tlsConfig, err := c.tlsConfig(t.profile)
+	if err != nil {
+		return nil, "", err
+	}
+	return tlsConfig, c.token(t.profile), nil
+}
+
+// tlsConfig returns the TLS settings of the profile or of the config
+// file, nil for plain connections.
+func (c config) tlsConfig(p profile) (*tls.Config, error) {
+	t := p.TLS
+	if t == nil {
+		t = c.TLS
+	}
+	if t == nil {
+		return nil, nil
+	}
+	return t.load()
+}
+
+// writeProfiles lists the profiles of the config file, marking the one
+// which would be used.
+func (c config) writeProfiles(w io.Writer, selected string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range sortedKeys(c.Profiles) {
+		mark := " "
+		if name == selected {
+			mark = "*"
+		}
+		p := c.Profiles[name]
+		address := p.Address
+		if address == "" {
+			address = c.address(p, "")
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\n", mark, name, address)
+	}
+	return tw.Flush()
+}
+
+// applyDefaults adds the flags of the command which were not given on the
+// command line. The first of these is used:
+//
+//  1. the flag on the command line
+//  2. the environment variable bound to the flag by the server
+//  3. the value for the command in the profile
+//  4. the value for any command in the profile
+//  5. the value for the command in the config file
+//  6. the value for any command in the config file
+//  7. the default declared by the server
+func (c config) applyDefaults(msg *console.CommandMessage, d console.CommandDescription, p profile) {
+	for _, f := range d.Flags {
+		if given(msg.Flags, f) {
+			continue
+		}
+		if values, ok := envValues(f); ok {
+			msg.Flags[f.Name] = values
+			continue
+		}
+		for _, defaults := range []flagDefaults{p.Commands[d.Name], p.Defaults, c.Commands[d.Name], c.Defaults} {
+			if values, ok := defaults[f.Name]; ok {
+				msg.Flags[f.Name] = values
+				break
+			}
+		}
+	}
+}
+
+func given(flags map[string][]string, f console.FlagDescription) bool {
+	for _, key := range []string{f.Name, f.Short, "no-" + f.Name} {
+		if _, ok := flags[key]; ok && key != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// envValues reads the variable bound to the flag, split on commas for
+// flags taking several values.
+func envValues(f console.FlagDescription) ([]string, bool) {
+	if f.Env == "" {
+		return nil, false
+	}
+	value, ok := os.LookupEnv(f.Env)
+	if !ok {
+		return nil, false
+	}
+	if strings.HasPrefix(f.Type, "[]") {
+		return strings.Split(value, ","), true
+	}
+	return []string{value}, true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestAddress(t *testing.T) {
+	c := config{profile: profile{Address: "config:1"}}
+	prod := profile{Address: "prod:1", Token: "prod-token"}
+	tests := []struct {
+		name     string
+		cfg      config
+		p        profile
+		option   string
+		env      string
+		expected string
+	}{
+		{"option", c, prod, "option:1", "env:1", "option:1"},
+		{"profile over environment", c, prod, "", "env:1", "prod:1"},
+		{"environment", c, profile{}, "", "env:1", "env:1"},
+		{"config", c, profile{}, "", "", "config:1"},
+		{"default", config{}, profile{}, "", "", "localhost:51005"},
+	}
+	for _, test := range tests {
+		t.Setenv("CMD_ADDRESS", test.env)
+		if address := test.cfg.address(test.p, test.option); address != test.expected {
+			t.Errorf("address() with %s = %q; want %q", test.name, address, test.expected)
+		}
+	}
+}
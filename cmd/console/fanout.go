@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/Kankeran/console"
+)
+
+const defaultParallel = 4
+
+// target is a server a command runs on, name prefixes its output when the
+// command runs on several targets.
+type target struct {
+	name    string
+	address string
+	profile profile
+	// anonymous targets are addresses typed in --targets, no token or TLS
+	// settings of the config are sent to them
+	anonymous bool
+}
+
+// targets resolves the comma separated list of --targets. An entry is the
+// name of a group of profiles, the name of a profile or an address. An
+// address is used with the flag defaults of the selected profile, but
+// without any credentials, a server needing them has to be a profile.
+func (c config) targets(list string, selected profile) ([]target, error) {
+	var targets []target
+	seen := make(map[string]bool)
+	add := func(name string) error {
+		if seen[name] {
+			return nil
+		}
+		seen[name] = true
+		if p, ok := c.Profiles[name]; ok {
+			if p.Address == "" {
+				return fmt.Errorf("profile %q has no address", name)
+			}
+			targets = append(targets, target{name: name, address: p.Address, profile: p})
+			return nil
+		}
+		targets = append(targets, target{name: name, address: name, profile: selected, anonymous: true})
+		return nil
+	}
+
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		members, isGroup := c.Groups[name]
+		if !isGroup {
+			members = []string{name}
+		}
+		for _, member := range members {
+			if err := add(member); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets in %q", list)
+	}
+	return targets, nil
+}
+
+// fanOut runs msg on every target, at most opts.parallel at a time, and
+// returns the exit code: 0 when the command succeeded everywhere.
+func fanOut(cfg config, opts options, msg console.CommandMessage, targets []target, stdin io.Reader, p *prompter) int {
+	parallel := opts.parallel
+	if parallel <= 0 {
+		parallel = defaultParallel
+	}
+
+	var outMu sync.Mutex
+	input := &sharedInput{r: stdin}
+	prompts := &serialPrompter{p: p}
+	errs := make([]error, len(targets))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t target) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			stdout := &prefixWriter{mu: &outMu, w: os.Stdout, prefix: t.name + " | "}
+			stderr := &prefixWriter{mu: &outMu, w: os.Stderr, prefix: t.name + " | "}
+			s := streams{
+				stdin:    input.reader(),
+				stdout:   stdout,
+				stderr:   stderr,
+				prompter: prompts.forTarget(t.name),
+				progress: newLogProgress(stderr),
+			}
+			errs[i] = execute(cfg, opts, cloneMessage(msg), t, s)
+			stdout.flush()
+			stderr.flush()
+		}(i, t)
+	}
+	wg.Wait()
+
+	return writeSummary(os.Stderr, targets, errs)
+}
+
+func writeSummary(w io.Writer, targets []target, errs []error) int {
+	failed := 0
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, t := range targets {
+		if errs[i] != nil {
+			failed++
+			fmt.Fprintf(tw, "%s\tfailed: %v\n", t.name, errs[i])
+			continue
+		}
+		fmt.Fprintf(tw, "%s\tok\n", t.name)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d of %d targets succeeded\n", len(targets)-failed, len(targets))
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func cloneMessage(msg console.CommandMessage) console.CommandMessage {
+	flags := make(map[string][]string, len(msg.Flags))
+	for key, values := range msg.Flags {
+		if values != nil {
+			values = append([]string(nil), values...)
+		}
+		flags[key] = values
+	}
+	return console.CommandMessage{Name: msg.Name, Flags: flags}
+}
+
+// prefixWriter writes whole lines, each starting with prefix, so that the
+// output of targets running at the same time does not interleave within
+// lines.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	i := bytes.LastIndexByte(p.buf, '\n')
+	if i < 0 {
+		return len(b), nil
+	}
+	lines := p.buf[:i+1]
+	p.buf = append([]byte(nil), p.buf[i+1:]...)
+	return len(b), p.write(lines)
+}
+
+// flush writes the last line if it does not end with a newline.
+func (p *prefixWriter) flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	lines := append(p.buf, '\n')
+	p.buf = nil
+	return p.write(lines)
+}
+
+func (p *prefixWriter) write(lines []byte) error {
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) > 0 {
+			out.WriteString(p.prefix)
+			out.Write(line)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := p.w.Write(out.Bytes())
+	return err
+}
+
+// sharedInput reads stdin once, when the first target asks for it, and
+// replays it to every target.
+type sharedInput struct {
+	r    io.Reader
+	once sync.Once
+	data []byte
+	err  error
+}
+
+func (s *sharedInput) load() ([]byte, error) {
+	s.once.Do(func() {
+		s.data, s.err = io.ReadAll(s.r)
+	})
+	return s.data, s.err
+}
+
+func (s *sharedInput) reader() io.Reader {
+	return &replayReader{s: s}
+}
+
+type replayReader struct {
+	s   *sharedInput
+	pos int
+}
+
+func (r *replayReader) Read(p []byte) (int, error) {
+	data, err := r.s.load()
+	if err != nil {
+		return 0, err
+	}
+	if r.pos >= len(data) {
+		return 0, io.EOF
+	}
+	n := copy(p, data[r.pos:])
+	r.pos += n
+	return n, nil
+}
+
+// serialPrompter asks the prompts of one target at a time, naming the
+// target in the question.
+type serialPrompter struct {
+	mu sync.Mutex
+	p  *prompter
+}
+
+func (s *serialPrompter) forTarget(name string) answerer {
+	return answerFunc(func(req console.PromptRequest) console.PromptReply {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		req.Question = fmt.Sprintf("[%s] %s", name, req.Question)
+		return s.p.answer(req)
+	})
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+)
+
+func TestTargets(t *testing.T) {
+	eu := profile{Address: "eu:1", Token: "eu-token"}
+	us := profile{Address: "us:1", Token: "us-token"}
+	selected := profile{Token: "selected-token"}
+	cfg := config{
+		Profiles: map[string]profile{"eu": eu, "us": us, "empty": {Token: "x"}},
+		Groups:   map[string][]string{"all": {"eu", "us"}, "mixed": {"us", "db:1"}},
+	}
+	tests := []struct {
+		list     string
+		expected []target
+		err      string
+	}{
+		{"eu", []target{{name: "eu", address: "eu:1", profile: eu}}, ""},
+		{"all", []target{{name: "eu", address: "eu:1", profile: eu}, {name: "us", address: "us:1", profile: us}}, ""},
+		{"db:1", []target{{name: "db:1", address: "db:1", profile: selected, anonymous: true}}, ""},
+		{" us , all,mixed,", []target{{name: "us", address: "us:1", profile: us}, {name: "eu", address: "eu:1", profile: eu}, {name: "db:1", address: "db:1", profile: selected, anonymous: true}}, ""},
+		{"empty", nil, `profile "empty" has no address`},
+		{"all,empty", nil, `profile "empty" has no address`},
+		{" , ", nil, `no targets in " , "`},
+	}
+	for _, test := range tests {
+		targets, err := cfg.targets(test.list, selected)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("targets(%q) error = %v; want %q", test.list, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(targets, test.expected) {
+			t.Errorf("targets(%q) = %+v, %v; want %+v", test.list, targets, err, test.expected)
+		}
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	cfg := config{profile: profile{Token: "config-token"}}
+	p := profile{Token: "prod-token"}
+	tests := []struct {
+		name     string
+		t        target
+		expected string
+	}{
+		{"profile", target{name: "prod", address: "prod:1", profile: p}, "prod-token"},
+		{"config", target{address: "localhost:51005"}, "config-token"},
+		{"address", target{name: "db:1", address: "db:1", profile: p, anonymous: true}, ""},
+	}
+	for _, test := range tests {
+		if _, token, err := cfg.credentials(test.t); err != nil || token != test.expected {
+			t.Errorf("credentials() of %s target = %q, %v; want %q", test.name, token, err, test.expected)
+		}
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	tests := []struct {
+		writes   []string
+		written  string
+		expected string
+	}{
+		{[]string{"a\n"}, "x | a\n", "x | a\n"},
+		{[]string{"a", "b", "c\nd"}, "x | abc\n", "x | abc\nx | d\n"},
+		{[]string{"a\nb\n\nc"}, "x | a\nx | b\nx | \n", "x | a\nx | b\nx | \nx | c\n"},
+		{[]string{"", "\n"}, "x | \n", "x | \n"},
+		{nil, "", ""},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		p := &prefixWriter{mu: &sync.Mutex{}, w: &b, prefix: "x | "}
+		for _, s := range test.writes {
+			if n, err := p.Write([]byte(s)); n != len(s) || err != nil {
+				t.Errorf("Write(%q) = %d, %v; want %d, nil", s, n, err, len(s))
+			}
+		}
+		if b.String() != test.written {
+			t.Errorf("writes %q wrote %q before flush; want %q", test.writes, b.String(), test.written)
+		}
+		if err := p.flush(); err != nil || b.String() != test.expected {
+			t.Errorf("writes %q wrote %q, %v after flush; want %q", test.writes, b.String(), err, test.expected)
+		}
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	targets := []target{{name: "eu"}, {name: "us-east"}}
+	tests := []struct {
+		errs     []error
+		code     int
+		expected string
+	}{
+		{[]error{nil, nil}, 0, "\neu       ok\nus-east  ok\n2 of 2 targets succeeded\n"},
+		{[]error{nil, errors.New("timeout")}, 1, "\neu       ok\nus-east  failed: timeout\n1 of 2 targets succeeded\n"},
+		{[]error{errors.New("denied"), errors.New("timeout")}, 1, "\neu       failed: denied\nus-east  failed: timeout\n0 of 2 targets succeeded\n"},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		if code := writeSummary(&b, targets, test.errs); code != test.code {
+			t.Errorf("writeSummary(%v) = %d; want %d", test.errs, code, test.code)
+		}
+		if b.String() != test.expected {
+			t.Errorf("writeSummary(%v) wrote %q; want %q", test.errs, b.String(), test.expected)
+		}
+	}
+}
+
+func TestSharedInput(t *testing.T) {
+	input := &sharedInput{r: iotest.OneByteReader(strings.NewReader("line 1\nline 2\n"))}
+	readers := []io.Reader{input.reader(), input.reader(), input.reader()}
+
+	var wg sync.WaitGroup
+	read := make([]string, len(readers))
+	for i, r := range readers {
+		wg.Add(1)
+		go func(i int, r io.Reader) {
+			defer wg.Done()
+			b, err := io.ReadAll(iotest.HalfReader(r))
+			if err != nil {
+				t.Errorf("ReadAll() error = %v", err)
+			}
+			read[i] = string(b)
+		}(i, r)
+	}
+	wg.Wait()
+	for i, s := range read {
+		if s != "line 1\nline 2\n" {
+			t.Errorf("reader %d read %q; want %q", i, s, "line 1\nline 2\n")
+		}
+	}
+
+	failing := &sharedInput{r: iotest.ErrReader(io.ErrUnexpectedEOF)}
+	for i := 0; i < 2; i++ {
+		if _, err := io.ReadAll(failing.reader()); err != io.ErrUnexpectedEOF {
+			t.Errorf("ReadAll() of a failing input error = %v; want %v", err, io.ErrUnexpectedEOF)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Kankeran/console"
+)
+
+// readFileValues replaces the values "@path" of flags the server reads from
+// files with the content of the file, "@-" with the content of stdin. A
+// leading "@@" sends the value with a single @. It reports whether stdin was
+// read, the command gets no input then.
+func readFileValues(msg *console.CommandMessage, d console.CommandDescription, stdin io.Reader) (bool, error) {
+	usedStdin := false
+	for key, values := range msg.Flags {
+		if f, ok := d.Flag(key); !ok || !f.FromFile {
+			continue
+		}
+		for i, value := range values {
+			path, ok := strings.CutPrefix(value, "@")
+			switch {
+			case !ok:
+				continue
+			case strings.HasPrefix(path, "@"):
+				values[i] = path
+				continue
+			case path == "-" && usedStdin:
+				return usedStdin, errors.New("stdin can be read by a single flag value")
+			}
+
+			var b []byte
+			var err error
+			if path == "-" {
+				usedStdin = true
+				b, err = io.ReadAll(stdin)
+			} else {
+				b, err = os.ReadFile(path)
+			}
+			if err != nil {
+				return usedStdin, err
+			}
+			values[i] = string(b)
+		}
+	}
+	return usedStdin, nil
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/Kankeran/console"
+)
+
+const genClientCommand = "gen-client"
+
+// goTypes maps the flag types of the server to the types of the options
+// structs, types without an entry are sent as strings.
+var goTypes = map[string]string{
+	"int":               "int",
+	"int8":              "int8",
+	"int16":             "int16",
+	"int32":             "int32",
+	"int64":             "int64",
+	"uint":              "uint",
+	"uint64":            "uint64",
+	"bool":              "bool",
+	"string":            "string",
+	"float32":           "float32",
+	"float64":           "float64",
+	"time.Duration":     "time.Duration",
+	"time.Time":         "time.Time",
+	"map[string]string": "map[string]string",
+}
+
+// generateClient writes a Go client with a method and an options struct
+// for every command of the description of a whole registry.
+func generateClient(w io.Writer, root console.CommandDescription, pkg string) error {
+	commands := commandsOf(root)
+	if err := checkNames(commands); err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	imports := map[string]bool{"context": true}
+	for _, d := range commands {
+		writeCommand(&body, d, imports)
+	}
+	if imports["sort"] {
+		body.WriteString(formatMapFunc)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by console %s. DO NOT EDIT.\n\n", genClientCommand)
+	fmt.Fprintf(&b, "package %s\n\nimport (\n", pkg)
+	for _, path := range sortedKeys(imports) {
+		fmt.Fprintf(&b, "\t%q\n", path)
+	}
+	b.WriteString("\n\t\"github.com/Kankeran/console\"\n)\n\n")
+	b.WriteString(clientType)
+	b.Write(body.Bytes())
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated client: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+const clientType = `// Client runs the commands of the server with typed options.
+type Client struct {
+	*console.Client
+}
+
+func NewClient(c *console.Client) *Client {
+	return &Client{Client: c}
+}
+
+`
+
+const formatMapFunc = `func formatMap(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for k, v := range m {
+		values = append(values, k+"="+v)
+	}
+	sort.Strings(values)
+	return values
+}
+`
+
+// commandsOf returns the commands which can run, groups only hold others.
+func commandsOf(d console.CommandDescription) []console.CommandDescription {
+	var commands []console.CommandDescription
+	if !d.Group && d.Name != "" {
+		commands = append(commands, d)
+	}
+	for _, sub := range d.Commands {
+		commands = append(commands, commandsOf(sub)...)
+	}
+	return commands
+}
+
+// checkNames reports commands and flags whose Go names are not valid or
+// are taken by another, e.g. "cache-flush" and "cache flush".
+func checkNames(commands []console.CommandDescription) error {
+	// the embedded *console.Client is the field Client of the client and
+	// promotes its methods, which a generated method would hide
+	methods := map[string]string{"Client": "the embedded console.Client"}
+	client := reflect.TypeOf(&console.Client{})
+	for i := 0; i < client.NumMethod(); i++ {
+		name := client.Method(i).Name
+		methods[name] = "console.Client." + name
+	}
+	var errs []error
+	for _, d := range commands {
+		name := goName(d.Name)
+		if other, ok := methods[name]; ok {
+			errs = append(errs, fmt.Errorf("command %q and %s both generate %s", d.Name, other, name))
+		} else if name == "" {
+			errs = append(errs, fmt.Errorf("command %q has no Go name", d.Name))
+		}
+		methods[name] = fmt.Sprintf("command %q", d.Name)
+
+		fields := make(map[string]string)
+		for _, f := range d.Flags {
+			field := goName(f.Name)
+			if other, ok := fields[field]; ok {
+				errs = append(errs, fmt.Errorf("flags --%s and --%s of %q both generate %s", f.Name, other, d.Name, field))
+			} else if field == "" {
+				errs = append(errs, fmt.Errorf("flag --%s of %q has no Go name", f.Name, d.Name))
+			}
+			fields[field] = f.Name
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeCommand writes the options struct and the method of d, adding the
+// packages it uses to imports.
+func writeCommand(b *bytes.Buffer, d console.CommandDescription, imports map[string]bool) {
+	name := goName(d.Name)
+
+	fmt.Fprintf(b, "// %sOptions are the flags of %q.\n", name, d.Name)
+	fmt.Fprintf(b, "type %sOptions struct {\n", name)
+	for _, f := range d.Flags {
+		typ := fieldType(f)
+		if strings.Contains(typ, "time.") {
+			imports["time"] = true
+		}
+		if doc := flagDoc(f); doc != "" {
+			fmt.Fprintf(b, "\t// %s\n", doc)
+		}
+		fmt.Fprintf(b, "\t%s %s\n", goName(f.Name), typ)
+	}
+	b.WriteString("}\n\n")
+
+	fmt.Fprintf(b, "// %s runs %q.", name, d.Name)
+	if d.Description != "" {
+		fmt.Fprintf(b, " %s.", strings.TrimSuffix(d.Description, "."))
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(b, "func (c *Client) %s(ctx context.Context, opts %sOptions) (console.Result, error) {\n", name, name)
+	b.WriteString("\tflags := make(map[string][]string)\n")
+	for _, f := range d.Flags {
+		writeFlag(b, f, imports)
+	}
+	fmt.Fprintf(b, "\treturn c.Client.Run(ctx, console.CommandMessage{Name: %q, Flags: flags})\n}\n\n", d.Name)
+}
+
+// fieldType is the type of the field of f. Optional flags are pointers or
+// nil slices and maps, so that the default of the server applies when they
+// are not set.
+func fieldType(f console.FlagDescription) string {
+	elem, isSlice := strings.CutPrefix(f.Type, "[]")
+	typ, ok := goTypes[elem]
+	if !ok {
+		typ = "string"
+	}
+	switch {
+	case isSlice:
+		return "[]" + typ
+	case strings.HasPrefix(typ, "map["), f.Required:
+		return typ
+	}
+	return "*" + typ
+}
+
+func writeFlag(b *bytes.Buffer, f console.FlagDescription, imports map[string]bool) {
+	field := "opts." + goName(f.Name)
+	elem, isSlice := strings.CutPrefix(f.Type, "[]")
+	typ := fieldType(f)
+	switch {
+	case strings.HasPrefix(typ, "map["):
+		imports["sort"] = true
+	case elem == "time.Time":
+		imports["time"] = true
+	default:
+		imports["fmt"] = true
+	}
+
+	switch {
+	case isSlice:
+		fmt.Fprintf(b, "\tfor _, v := range %s {\n\t\tflags[%q] = append(flags[%q], %s)\n\t}\n", field, f.Name, f.Name, formatValue(elem, "v"))
+	case strings.HasPrefix(typ, "map["):
+		fmt.Fprintf(b, "\tif %s != nil {\n\t\tflags[%q] = formatMap(%s)\n\t}\n", field, f.Name, field)
+	case f.Required:
+		fmt.Fprintf(b, "\tflags[%q] = []string{%s}\n", f.Name, formatValue(elem, field))
+	default:
+		fmt.Fprintf(b, "\tif %s != nil {\n\t\tflags[%q] = []string{%s}\n\t}\n", field, f.Name, formatValue(elem, "*"+field))
+	}
+}
+
+func formatValue(flagType, expr string) string {
+	if flagType == "time.Time" {
+		if strings.HasPrefix(expr, "*") {
+			expr = "(" + expr + ")"
+		}
+		return expr + ".Format(time.RFC3339Nano)"
+	}
+	return "fmt.Sprint(" + expr + ")"
+}
+
+func flagDoc(f console.FlagDescription) string {
+	var parts []string
+	if f.Description != "" {
+		parts = append(parts, strings.TrimSuffix(f.Description, ".")+".")
+	}
+	if _, ok := goTypes[strings.TrimPrefix(f.Type, "[]")]; !ok {
+		parts = append(parts, "A "+f.Type+".")
+	}
+	if len(f.Choices) > 0 {
+		parts = append(parts, "One of "+strings.Join(f.Choices, ", ")+".")
+	}
+	if f.Default != "" {
+		parts = append(parts, "Defaults to "+f.Default+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+// goName turns "cache flush" or "dry-run" into CacheFlush and DryRun.
+func goName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && unicode.IsDigit(r) {
+			b.WriteByte('X')
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// genClient runs "console gen-client [--package name] [--out file]",
+// describing the registry of the server and writing its client.
+func genClient(cfg config, t target, msg console.CommandMessage) error {
+	pkg, out := "client", ""
+	if v := msg.Flags["package"]; len(v) > 0 {
+		pkg = v[len(v)-1]
+	}
+	if v := msg.Flags["out"]; len(v) > 0 {
+		out = v[len(v)-1]
+	}
+
+	tlsConfig, token, err := cfg.credentials(t)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	client, err := console.Dial(ctx, t.address, console.WithTLS(tlsConfig), console.WithToken(token))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	root, err := client.Describe(ctx, "")
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		return generateClient(os.Stdout, root, pkg)
+	}
+
+	var b bytes.Buffer
+	if err := generateClient(&b, root, pkg); err != nil {
+		return err
+	}
+	return os.WriteFile(out, b.Bytes(), 0o644)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kankeran/console"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// testRegistry describes a server with flags of every kind.
+func testRegistry(t *testing.T) console.CommandDescription {
+	t.Helper()
+
+	r := console.NewRegistry()
+	onExec := func(in console.Input, out console.Output) error { return nil }
+	cache, _ := r.RegisterGroup("cache", "Cache commands")
+	cache.OptionalString("region", "Region of the cache", "eu")
+	flush, _ := cache.RegisterCommand("flush", "Flushes the cache", onExec)
+	flush.RequiredInt("level", "").
+		OptionalBool("dry-run", "Only report what would be flushed", false).
+		OptionalEnum("mode", "", "soft", "soft", "hard").
+		OptionalSliceString("key", "", nil).
+		OptionalSliceDuration("wait", "", nil).
+		OptionalTime("at", "", time.Time{}).
+		OptionalStringMap("label", "", nil).
+		OptionalByteSize("size", "", 0).
+		RequiredTime("since", "")
+	r.RegisterCommand("status", "", onExec)
+
+	d, err := r.Describe("")
+	if err != nil {
+		t.Fatalf("Describe(\"\") error = %v", err)
+	}
+	return d
+}
+
+func TestGenerateClient(t *testing.T) {
+	var b bytes.Buffer
+	if err := generateClient(&b, testRegistry(t), "client"); err != nil {
+		t.Fatalf("generateClient() error = %v", err)
+	}
+
+	golden := filepath.Join("testdata", "client.go.golden")
+	if *update {
+		if err := os.WriteFile(golden, b.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	if !bytes.Equal(b.Bytes(), expected) {
+		t.Errorf("generateClient() =\n%s\nwant\n%s", b.Bytes(), expected)
+	}
+}
+
+func TestGenerateClientCompiles(t *testing.T) {
+	var b bytes.Buffer
+	if err := generateClient(&b, testRegistry(t), "client"); err != nil {
+		t.Fatalf("generateClient() error = %v", err)
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "client.go", b.Bytes(), 0)
+	if err != nil {
+		t.Fatalf("parsing generated client: %v", err)
+	}
+	// the methods of the embedded console.Client stay usable
+	use, err := parser.ParseFile(fset, "use.go", `package client
+
+import "context"
+
+func use(ctx context.Context, c *Client) error {
+	if _, err := c.Describe(ctx, "status"); err != nil {
+		return err
+	}
+	if _, err := c.Status(ctx, StatusOptions{}); err != nil {
+		return err
+	}
+	return c.Close()
+}
+`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("client", fset, []*ast.File{f, use}, nil); err != nil {
+		t.Errorf("type checking generated client: %v\n%s", err, b.Bytes())
+	}
+}
+
+func TestGenerateClientNameCollisions(t *testing.T) {
+	onExec := func(in console.Input, out console.Output) error { return nil }
+	tests := []struct {
+		name     string
+		register func(r *console.Registry)
+		expected string
+	}{
+		{"commands", func(r *console.Registry) {
+			r.RegisterCommand("cache-flush", "", onExec)
+			cache, _ := r.RegisterGroup("cache", "")
+			cache.RegisterCommand("flush", "", onExec)
+		}, `both generate CacheFlush`},
+		{"flags", func(r *console.Registry) {
+			cmd, _ := r.RegisterCommand("asd", "", onExec)
+			cmd.OptionalBool("dry-run", "", false).OptionalBool("dry_run", "", false)
+		}, `both generate DryRun`},
+		{"embedded client", func(r *console.Registry) {
+			r.RegisterCommand("client", "", onExec)
+		}, `the embedded console.Client both generate Client`},
+		{"method Close", func(r *console.Registry) {
+			r.RegisterCommand("close", "", onExec)
+		}, `command "close" and console.Client.Close both generate Close`},
+		{"method Run", func(r *console.Registry) {
+			r.RegisterCommand("run", "", onExec)
+		}, `command "run" and console.Client.Run both generate Run`},
+		{"method Describe", func(r *console.Registry) {
+			r.RegisterCommand("describe", "", onExec)
+		}, `command "describe" and console.Client.Describe both generate Describe`},
+		{"no name", func(r *console.Registry) {
+			r.RegisterCommand("_", "", onExec)
+		}, `command "_" has no Go name`},
+	}
+	for _, test := range tests {
+		r := console.NewRegistry()
+		test.register(r)
+		d, _ := r.Describe("")
+		var b bytes.Buffer
+		if err := generateClient(&b, d, "client"); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("generateClient() with colliding %s error = %v; want %q", test.name, err, test.expected)
+		}
+	}
+}
@@ -1,42 +1,154 @@
 package main
 
 import (
-	"encoding/binary"
+	"context"
+	"errors"
 	"fmt"
-	"net"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/Kankeran/console"
 )
 
+// usageError is reported with exit code 2, like errors in the arguments.
+type usageError struct {
+	error
+}
+
 func main() {
-	conn, err := net.Dial("tcp", console.GetAdress())
+	opts, msg, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "console:", err)
+		os.Exit(2)
+	}
+
+	if opts.completion != "" {
+		if err := writeCompletion(os.Stdout, opts.completion); err != nil {
+			fmt.Fprintln(os.Stderr, "console:", err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	var stdin io.Reader = os.Stdin
+	if opts.inputFile != "" {
+		f, err := os.Open(opts.inputFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "console:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		stdin = f
+	}
+
+	cfg, err := loadConfig()
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, "console:", err)
+		os.Exit(2)
+	}
+	profileName, prof, err := cfg.selectedProfile(opts.profile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "console:", err)
+		os.Exit(2)
+	}
+
+	// "console profiles" lists the profiles of the config file instead of
+	// running a command
+	if msg.Name == profilesCommand {
+		if err := cfg.writeProfiles(os.Stdout, profileName); err != nil {
+			fmt.Fprintln(os.Stderr, "console:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// "console gen-client" writes a typed Go client for the commands of the
+	// server
+	if msg.Name == genClientCommand {
+		t := target{address: cfg.address(prof, opts.address), profile: prof}
+		if err := genClient(cfg, t, msg); err != nil {
+			fmt.Fprintln(os.Stderr, "console:", err)
+			os.Exit(1)
+		}
+		return
 	}
-	defer conn.Close()
 
-	msg := console.CommandMessage{
-		Name: "something",
-		Flags: map[string][]string{
-			"asd2": {"12"},
-		},
+	p := newPrompter(opts.assumeYes)
+	defer p.Close()
+
+	if opts.targets != "" {
+		targets, err := cfg.targets(opts.targets, prof)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "console:", err)
+			os.Exit(2)
+		}
+		os.Exit(fanOut(cfg, opts, msg, targets, stdin, p))
 	}
 
-	requestBody := msg.ToBytes()
-	request := make([]byte, 0, len(requestBody)+4)
-	request = binary.BigEndian.AppendUint32(request, uint32(len(requestBody)))
-	fmt.Println(request)
-	request = append(request, requestBody...)
+	s := streams{
+		stdin:    stdin,
+		stdout:   os.Stdout,
+		stderr:   os.Stderr,
+		prompter: p,
+		progress: newProgress(os.Stderr),
+	}
+	target := target{address: cfg.address(prof, opts.address), profile: prof}
+	if err := execute(cfg, opts, msg, target, s); err != nil {
+		fmt.Fprintln(os.Stderr, "console:", err)
+		if errors.As(err, &usageError{}) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
 
-	fmt.Println(len(requestBody))
-	conn.Write(request)
+// execute runs msg on target and renders its results.
+func execute(cfg config, opts options, msg console.CommandMessage, t target, s streams) error {
+	ctx := context.Background()
+	tlsConfig, token, err := cfg.credentials(t)
+	if err != nil {
+		return err
+	}
 
-	b := make([]byte, 1024)
-	n, err := conn.Read(b)
+	var res results
+	stdin := &inputReader{Reader: s.stdin}
+	clientOpts := append(s.clientOptions(stdin, opts.logLevel, &res),
+		console.WithTLS(tlsConfig),
+		console.WithToken(token))
+	client, err := console.Dial(ctx, t.address, clientOpts...)
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
-	b = b[:n]
+	defer client.Close()
 
-	fmt.Println(string(b))
+	if msg.Name != completeCommand {
+		d, err := client.Describe(ctx, msg.Name)
+		switch {
+		case errors.Is(err, console.ErrUnknownCommand):
+			// the server reports it when running the command
+		case err != nil:
+			return err
+		default:
+			flags, err := parseFlags(opts.flagArgs, &d)
+			if err != nil {
+				return usageError{err}
+			}
+			msg.Flags = flags
+			cfg.applyDefaults(&msg, d, t.profile)
+			usedStdin, err := readFileValues(&msg, d, stdin.Reader)
+			if err != nil {
+				return usageError{err}
+			}
+			if usedStdin {
+				stdin.Reader = strings.NewReader("")
+			}
+		}
+	}
+
+	_, err = client.Run(ctx, msg)
+	if renderErr := res.render(s.stdout, opts.output); err == nil {
+		err = renderErr
+	}
+	return err
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Kankeran/console"
+)
+
+const (
+	progressBarWidth    = 30
+	progressLogInterval = 5 * time.Second
+)
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// progress renders the tasks reported by the server. On a terminal the
+// running tasks are kept as live bars at the bottom of the output,
+// otherwise a log line per task is printed every progressLogInterval.
+type progress struct {
+	w      io.Writer
+	live   bool
+	tasks  []console.ProgressUpdate
+	logged map[string]time.Time
+	drawn  int
+}
+
+func newProgress(w *os.File) *progress {
+	return &progress{
+		w:      w,
+		live:   isTerminal(w),
+		logged: make(map[string]time.Time),
+	}
+}
+
+// newLogProgress reports progress as log lines only.
+func newLogProgress(w io.Writer) *progress {
+	return &progress{w: w, logged: make(map[string]time.Time)}
+}
+
+func (p *progress) update(u console.ProgressUpdate) {
+	if !p.live {
+		p.log(u)
+		return
+	}
+
+	p.clear()
+	i := p.index(u.Name)
+	switch {
+	case u.Done && i >= 0:
+		p.tasks = append(p.tasks[:i], p.tasks[i+1:]...)
+		fmt.Fprintln(p.w, formatProgress(u))
+	case u.Done:
+		fmt.Fprintln(p.w, formatProgress(u))
+	case i >= 0:
+		p.tasks[i] = u
+	default:
+		p.tasks = append(p.tasks, u)
+	}
+	p.redraw()
+}
+
+func (p *progress) index(name string) int {
+	for i, t := range p.tasks {
+		if t.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *progress) log(u console.ProgressUpdate) {
+	last, seen := p.logged[u.Name]
+	if seen && !u.Done && time.Since(last) < progressLogInterval {
+		return
+	}
+	p.logged[u.Name] = time.Now()
+	if u.Done {
+		delete(p.logged, u.Name)
+	}
+	fmt.Fprintf(p.w, "[PROGRESS] %s\n", formatProgress(u))
+}
+
+// clear removes the live bars so that other output can be written; it is
+// followed by redraw once that output is written.
+func (p *progress) clear() {
+	if p.drawn == 0 {
+		return
+	}
+	fmt.Fprintf(p.w, "\x1b[%dA\x1b[J", p.drawn)
+	p.drawn = 0
+}
+
+func (p *progress) redraw() {
+	for _, t := range p.tasks {
+		fmt.Fprintln(p.w, formatProgress(t))
+	}
+	p.drawn = len(p.tasks)
+}
+
+func formatProgress(u console.ProgressUpdate) string {
+	var b strings.Builder
+	b.WriteString(u.Name)
+
+	switch {
+	case u.Total > 0:
+		done := int(float64(progressBarWidth) * float64(u.Current) / float64(u.Total))
+		// commands may report more than the total or a negative current
+		switch {
+		case done > progressBarWidth:
+			done = progressBarWidth
+		case done < 0:
+			done = 0
+		}
+		fmt.Fprintf(&b, " [%s%s] %3d%% %d/%d",
+			strings.Repeat("=", done), strings.Repeat(" ", progressBarWidth-done),
+			100*u.Current/u.Total, u.Current, u.Total)
+	case u.Current > 0:
+		fmt.Fprintf(&b, " %d", u.Current)
+	}
+
+	if u.Done {
+		b.WriteString(" done")
+	} else if eta := u.ETA.Round(time.Second); eta > 0 {
+		fmt.Fprintf(&b, " eta %s", eta)
+	}
+	if u.Status != "" {
+		b.WriteString(" " + u.Status)
+	}
+	return b.String()
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kankeran/console"
+)
+
+func TestFormatProgress(t *testing.T) {
+	tests := []struct {
+		u        console.ProgressUpdate
+		expected string
+	}{
+		{console.ProgressUpdate{Name: "copy", Current: 15, Total: 30, ETA: 2 * time.Second},
+			"copy [===============               ]  50% 15/30 eta 2s"},
+		{console.ProgressUpdate{Name: "copy", Current: 45, Total: 30, Done: true},
+			"copy [==============================] 150% 45/30 done"},
+		{console.ProgressUpdate{Name: "copy", Current: -10, Total: 30},
+			"copy [                              ] -33% -10/30"},
+		{console.ProgressUpdate{Name: "scan", Current: 7, Status: "disk 2"},
+			"scan 7 disk 2"},
+		{console.ProgressUpdate{Name: "scan", Current: -1}, "scan"},
+	}
+	for _, test := range tests {
+		if s := formatProgress(test.u); s != test.expected {
+			t.Errorf("formatProgress(%+v) = %q; want %q", test.u, s, test.expected)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Kankeran/console"
+)
+
+var errNoTerminal = errors.New("no terminal available to answer the prompt, rerun with --yes or interactively")
+
+// prompter answers prompts of the server on the terminal of the operator.
+// It uses /dev/tty so that prompts keep working while stdin is streamed
+// to the command.
+type prompter struct {
+	assumeYes bool
+	tty       *os.File
+	in        *bufio.Reader
+	out       io.Writer
+}
+
+func newPrompter(assumeYes bool) *prompter {
+	p := &prompter{assumeYes: assumeYes}
+	if assumeYes {
+		return p
+	}
+
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		p.tty, p.in, p.out = tty, bufio.NewReader(tty), tty
+	}
+	return p
+}
+
+func (p *prompter) Close() error {
+	if p.tty == nil {
+		return nil
+	}
+	return p.tty.Close()
+}
+
+// answerer answers the prompts of a command.
+type answerer interface {
+	answer(req console.PromptRequest) console.PromptReply
+}
+
+type answerFunc func(req console.PromptRequest) console.PromptReply
+
+func (f answerFunc) answer(req console.PromptRequest) console.PromptReply {
+	return f(req)
+}
+
+func (p *prompter) answer(req console.PromptRequest) console.PromptReply {
+	value, err := p.ask(req)
+	if err != nil {
+		return console.PromptReply{Err: err.Error()}
+	}
+	return console.PromptReply{Value: value}
+}
+
+func (p *prompter) ask(req console.PromptRequest) (string, error) {
+	if p.assumeYes {
+		switch {
+		case req.Kind == console.PromptConfirm:
+			return "true", nil
+		case req.Default != "":
+			return req.Default, nil
+		}
+		return "", fmt.Errorf("%q needs an answer, which --yes cannot provide", req.Question)
+	}
+	if p.tty == nil {
+		return "", errNoTerminal
+	}
+
+	switch req.Kind {
+	case console.PromptSecret:
+		return p.askSecret(req.Question)
+	case console.PromptSelect:
+		return p.askSelect(req)
+	case console.PromptConfirm:
+		return p.askConfirm(req.Question)
+	}
+	return p.askText(req.Question, req.Default)
+}
+
+func (p *prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (p *prompter) askText(question, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+
+	line, err := p.readLine()
+	if err != nil {
+		return "", err
+	}
+	if line == "" {
+		return defaultValue, nil
+	}
+	return line, nil
+}
+
+func (p *prompter) askSecret(question string) (string, error) {
+	fmt.Fprintf(p.out, "%s: ", question)
+
+	if err := p.stty("-echo"); err != nil {
+		return "", err
+	}
+	line, err := p.readLine()
+	p.stty("echo")
+	fmt.Fprintln(p.out)
+
+	return line, err
+}
+
+func (p *prompter) stty(args ...string) error {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = p.tty
+	return cmd.Run()
+}
+
+func (p *prompter) askSelect(req console.PromptRequest) (string, error) {
+	for i, choice := range req.Choices {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, choice)
+	}
+
+	for {
+		line, err := p.askText(req.Question, req.Default)
+		if err != nil {
+			return "", err
+		}
+		if i, err := strconv.Atoi(line); err == nil && i >= 1 && i <= len(req.Choices) {
+			return req.Choices[i-1], nil
+		}
+		for _, choice := range req.Choices {
+			if line == choice {
+				return choice, nil
+			}
+		}
+		fmt.Fprintf(p.out, "%q is not one of the choices\n", line)
+	}
+}
+
+func (p *prompter) askConfirm(question string) (string, error) {
+	fmt.Fprintf(p.out, "%s [y/N]: ", question)
+
+	line, err := p.readLine()
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return "true", nil
+	}
+	return "false", nil
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Kankeran/console"
+)
+
+var outputFormats = []string{"text", "json", "yaml", "table", "csv"}
+
+func checkOutputFormat(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// results collects the structured entries of a command until it exits,
+// since most formats need to see all of them before printing anything.
+type results struct {
+	records []object
+	items   []json.RawMessage
+	values  object
+}
+
+func (r *results) add(e console.Entry) {
+	switch e.Kind {
+	case console.EntryRecord:
+		r.records = append(r.records, e.Fields)
+	case console.EntryItem:
+		for _, f := range e.Fields {
+			r.items = append(r.items, raw(f.Value))
+		}
+	case console.EntryKeyValue:
+		r.values = append(r.values, e.Fields...)
+	}
+}
+
+func (r *results) empty() bool {
+	return len(r.records) == 0 && len(r.items) == 0 && len(r.values) == 0
+}
+
+func (r *results) render(w io.Writer, format string) error {
+	if r.empty() {
+		return nil
+	}
+
+	switch format {
+	case "json":
+		return r.renderJSON(w)
+	case "yaml":
+		return r.renderYAML(w)
+	case "table":
+		return r.renderTable(w)
+	case "csv":
+		return r.renderCSV(w)
+	}
+	return r.renderText(w)
+}
+
+// document returns the single group of entries the command produced, or an
+// object holding every group when it produced several kinds.
+func (r *results) document() any {
+	var doc object
+	if len(r.records) > 0 {
+		doc = append(doc, console.Field{Key: "records", Value: r.records})
+	}
+	if len(r.items) > 0 {
+		doc = append(doc, console.Field{Key: "items", Value: r.items})
+	}
+	if len(r.values) > 0 {
+		doc = append(doc, console.Field{Key: "values", Value: r.values})
+	}
+	if len(doc) == 1 {
+		return doc[0].Value
+	}
+	return doc
+}
+
+func (r *results) renderJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r.document(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+func (r *results) renderYAML(w io.Writer) error {
+	var buf bytes.Buffer
+	if err := writeYAML(&buf, toYAMLValue(r.document()), 0); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (r *results) tables() [][][]string {
+	var tables [][][]string
+	if len(r.records) > 0 {
+		var columns []string
+		seen := make(map[string]bool)
+		for _, rec := range r.records {
+			for _, f := range rec {
+				if !seen[f.Key] {
+					seen[f.Key] = true
+					columns = append(columns, f.Key)
+				}
+			}
+		}
+
+		rows := [][]string{columns}
+		for _, rec := range r.records {
+			row := make([]string, len(columns))
+			for i, column := range columns {
+				if f, ok := rec.get(column); ok {
+					row[i] = scalar(raw(f))
+				}
+			}
+			rows = append(rows, row)
+		}
+		tables = append(tables, rows)
+	}
+	if len(r.items) > 0 {
+		rows := [][]string{{"value"}}
+		for _, item := range r.items {
+			rows = append(rows, []string{scalar(item)})
+		}
+		tables = append(tables, rows)
+	}
+	if len(r.values) > 0 {
+		rows := [][]string{{"key", "value"}}
+		for _, f := range r.values {
+			rows = append(rows, []string{f.Key, scalar(raw(f.Value))})
+		}
+		tables = append(tables, rows)
+	}
+	return tables
+}
+
+func (r *results) renderTable(w io.Writer) error {
+	for i, rows := range r.tables() {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for j, row := range rows {
+			if j == 0 {
+				row = upper(row)
+			}
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *results) renderCSV(w io.Writer) error {
+	for i, rows := range r.tables() {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *results) renderText(w io.Writer) error {
+	for i, rec := range r.records {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		for _, f := range rec {
+			fmt.Fprintf(w, "%s: %s\n", f.Key, scalar(raw(f.Value)))
+		}
+	}
+	for _, item := range r.items {
+		fmt.Fprintln(w, scalar(item))
+	}
+	for _, f := range r.values {
+		fmt.Fprintf(w, "%s: %s\n", f.Key, scalar(raw(f.Value)))
+	}
+	return nil
+}
+
+// object keeps the order of fields in which the command emitted them.
+type object []console.Field
+
+func (o object) get(key string) (any, bool) {
+	for _, f := range o {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.Key)
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func raw(v any) json.RawMessage {
+	if r, ok := v.(json.RawMessage); ok {
+		return r
+	}
+	b, _ := json.Marshal(v)
+	return b
+}
+
+func scalar(r json.RawMessage) string {
+	var s string
+	if json.Unmarshal(r, &s) == nil {
+		return s
+	}
+	var buf bytes.Buffer
+	if json.Compact(&buf, r) != nil {
+		return string(r)
+	}
+	return buf.String()
+}
+
+func upper(row []string) []string {
+	out := make([]string, len(row))
+	for i, s := range row {
+		out[i] = strings.ToUpper(s)
+	}
+	return out
+}
+
+// toYAMLValue turns the document into plain values, keeping emitted field
+// order for objects and sorting the keys of maps nested inside values.
+func toYAMLValue(v any) any {
+	switch v := v.(type) {
+	case object:
+		out := make(object, len(v))
+		for i, f := range v {
+			out[i] = console.Field{Key: f.Key, Value: toYAMLValue(f.Value)}
+		}
+		return out
+	case []object:
+		out := make([]any, len(v))
+		for i, o := range v {
+			out[i] = toYAMLValue(o)
+		}
+		return out
+	case []json.RawMessage:
+		out := make([]any, len(v))
+		for i, r := range v {
+			out[i] = toYAMLValue(r)
+		}
+		return out
+	case json.RawMessage:
+		var decoded any
+		dec := json.NewDecoder(bytes.NewReader(v))
+		dec.UseNumber()
+		if dec.Decode(&decoded) != nil {
+			return string(v)
+		}
+		return toYAMLValue(decoded)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make(object, len(keys))
+		for i, k := range keys {
+			out[i] = console.Field{Key: k, Value: toYAMLValue(v[k])}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = toYAMLValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+func writeYAML(buf *bytes.Buffer, v any, indent int) error {
+	pad := strings.Repeat("  ", indent)
+	switch v := v.(type) {
+	case object:
+		if len(v) == 0 {
+			buf.WriteString(pad + "{}\n")
+			return nil
+		}
+		for _, f := range v {
+			buf.WriteString(pad + yamlString(f.Key) + ":")
+			if err := writeYAMLChild(buf, f.Value, indent); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if len(v) == 0 {
+			buf.WriteString(pad + "[]\n")
+			return nil
+		}
+		for _, e := range v {
+			if o, ok := e.(object); ok && len(o) > 0 {
+				// "- " takes the place of the indent of the first key.
+				var item bytes.Buffer
+				if err := writeYAML(&item, o, indent+1); err != nil {
+					return err
+				}
+				buf.WriteString(pad + "- ")
+				buf.Write(item.Bytes()[len(pad)+2:])
+				continue
+			}
+			buf.WriteString(pad + "-")
+			if err := writeYAMLChild(buf, e, indent); err != nil {
+				return err
+			}
+		}
+	default:
+		buf.WriteString(pad + yamlScalar(v) + "\n")
+	}
+	return nil
+}
+
+func writeYAMLChild(buf *bytes.Buffer, v any, indent int) error {
+	switch c := v.(type) {
+	case object:
+		if len(c) > 0 {
+			buf.WriteByte('\n')
+			return writeYAML(buf, c, indent+1)
+		}
+		buf.WriteString(" {}\n")
+	case []any:
+		if len(c) > 0 {
+			buf.WriteByte('\n')
+			return writeYAML(buf, c, indent+1)
+		}
+		buf.WriteString(" []\n")
+	default:
+		buf.WriteString(" " + yamlScalar(v) + "\n")
+	}
+	return nil
+}
+
+func yamlScalar(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		return yamlString(v)
+	}
+	return yamlString(fmt.Sprint(v))
+}
+
+// yamlString quotes every string but plain identifiers, like "eu-west" or
+// "v1.2", since YAML parsers read many unquoted strings as other types,
+// e.g. 0x10, .inf, 2024-01-01 or y.
+func yamlString(s string) string {
+	if !isIdentifier(s) {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "null", "true", "false", "yes", "no", "on", "off", "y", "n":
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// isIdentifier reports whether s is a letter or underscore followed by
+// letters, digits, underscores, dashes or dots.
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', r == '_':
+		case i > 0 && ('0' <= r && r <= '9' || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
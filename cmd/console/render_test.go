@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Kankeran/console"
+)
+
+// entry encodes fields the way the server sends them, so their values hold
+// JSON like the client receives.
+func entry(t *testing.T, kind console.EntryKind, fields ...console.Field) console.Entry {
+	t.Helper()
+
+	b, err := console.Entry{Kind: kind, Fields: fields}.ToBytes()
+	if err != nil {
+		t.Fatalf("ToBytes() error = %v", err)
+	}
+	e, err := console.EntryFromBytes(b)
+	if err != nil {
+		t.Fatalf("EntryFromBytes() error = %v", err)
+	}
+	return e
+}
+
+func TestRender(t *testing.T) {
+	var records, items, values results
+	records.add(entry(t, console.EntryRecord,
+		console.Field{Key: "name", Value: "web-1"},
+		console.Field{Key: "port", Value: 8080},
+		console.Field{Key: "tags", Value: []string{"a", "b"}}))
+	records.add(entry(t, console.EntryRecord,
+		console.Field{Key: "name", Value: "0x10"},
+		console.Field{Key: "up", Value: false}))
+	items.add(entry(t, console.EntryItem, console.Field{Value: "y"}))
+	items.add(entry(t, console.EntryItem, console.Field{Value: "a,b"}))
+	values.add(entry(t, console.EntryKeyValue, console.Field{Key: "since", Value: "2024-01-01"}))
+	values.add(entry(t, console.EntryKeyValue, console.Field{Key: "limit", Value: map[string]any{"max": ".inf", "min": nil}}))
+
+	tests := []struct {
+		name     string
+		r        *results
+		format   string
+		expected string
+	}{
+		{"records", &records, "json", `[
+  {
+    "name": "web-1",
+    "port": 8080,
+    "tags": [
+      "a",
+      "b"
+    ]
+  },
+  {
+    "name": "0x10",
+    "up": false
+  }
+]
+`},
+		{"records", &records, "yaml", `- name: web-1
+  port: 8080
+  tags:
+    - a
+    - b
+- name: "0x10"
+  up: false
+`},
+		{"records", &records, "table", "NAME   PORT  TAGS       UP\n" +
+			"web-1  8080  [\"a\",\"b\"]  \n" +
+			"0x10                    false\n"},
+		{"records", &records, "csv", `name,port,tags,up
+web-1,8080,"[""a"",""b""]",
+0x10,,,false
+`},
+		{"records", &records, "text", `name: web-1
+port: 8080
+tags: ["a","b"]
+
+name: 0x10
+up: false
+`},
+		{"items", &items, "json", `[
+  "y",
+  "a,b"
+]
+`},
+		{"items", &items, "yaml", `- "y"
+- "a,b"
+`},
+		{"items", &items, "table", `VALUE
+y
+a,b
+`},
+		{"items", &items, "csv", `value
+y
+"a,b"
+`},
+		{"items", &items, "text", `y
+a,b
+`},
+		{"values", &values, "json", `{
+  "since": "2024-01-01",
+  "limit": {
+    "max": ".inf",
+    "min": null
+  }
+}
+`},
+		{"values", &values, "yaml", `since: "2024-01-01"
+limit:
+  max: ".inf"
+  min: null
+`},
+		{"values", &values, "table", `KEY    VALUE
+since  2024-01-01
+limit  {"max":".inf","min":null}
+`},
+		{"values", &values, "csv", `key,value
+since,2024-01-01
+limit,"{""max"":"".inf"",""min"":null}"
+`},
+		{"values", &values, "text", `since: 2024-01-01
+limit: {"max":".inf","min":null}
+`},
+		{"no entries", &results{}, "json", ""},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := test.r.render(&b, test.format); err != nil {
+			t.Errorf("render(%s) of %s error = %v", test.format, test.name, err)
+			continue
+		}
+		if b.String() != test.expected {
+			t.Errorf("render(%s) of %s =\n%s\nwant\n%s", test.format, test.name, b.String(), test.expected)
+		}
+	}
+}
+
+func TestYAMLString(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected string
+	}{
+		{"eu-west", "eu-west"},
+		{"v1.2", "v1.2"},
+		{"_id", "_id"},
+		{"", `""`},
+		{"0x10", `"0x10"`},
+		{"0o17", `"0o17"`},
+		{"1e3", `"1e3"`},
+		{".inf", `".inf"`},
+		{"-.Inf", `"-.Inf"`},
+		{".NaN", `".NaN"`},
+		{"2024-01-01", `"2024-01-01"`},
+		{"12:30:00", `"12:30:00"`},
+		{"y", `"y"`},
+		{"N", `"N"`},
+		{"Yes", `"Yes"`},
+		{"off", `"off"`},
+		{"null", `"null"`},
+		{"~", `"~"`},
+		{"a b", `"a b"`},
+		{"a: b", `"a: b"`},
+		{"- a", `"- a"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{`say "hi"`, `"say \"hi\""`},
+	}
+	for _, test := range tests {
+		if s := yamlString(test.s); s != test.expected {
+			t.Errorf("yamlString(%q) = %s; want %s", test.s, s, test.expected)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Kankeran/console"
+)
+
+// streams are where a command run on a target reads and writes.
+type streams struct {
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	prompter answerer
+	progress *progress
+}
+
+// inputReader lets the input of a command be replaced after the client is
+// created, e.g. when stdin was read as the value of a flag.
+type inputReader struct {
+	io.Reader
+}
+
+// clientOptions wires the frames of the server to the terminal: output is
+// written above live progress bars, log records below logLevel are dropped
+// and structured entries are collected into results.
+func (s streams) clientOptions(stdin io.Reader, logLevel console.LogLevel, res *results) []console.ClientOption {
+	stdout := &progressWriter{p: s.progress, w: s.stdout}
+	stderr := &progressWriter{p: s.progress, w: s.stderr}
+	return []console.ClientOption{
+		console.WithStdin(stdin),
+		console.WithStdout(stdout),
+		console.WithStderr(stderr),
+		console.WithLogHandler(func(rec console.LogRecord) {
+			if rec.Level >= logLevel {
+				fmt.Fprintf(stderr, "[%s] %s\n", rec.Level, rec.Message)
+			}
+		}),
+		console.WithProgressHandler(s.progress.update),
+		console.WithEntryHandler(res.add),
+		console.WithPromptHandler(func(req console.PromptRequest) console.PromptReply {
+			s.progress.clear()
+			defer s.progress.redraw()
+			return s.prompter.answer(req)
+		}),
+	}
+}
+
+// progressWriter keeps live progress bars below the output of the command.
+type progressWriter struct {
+	p *progress
+	w io.Writer
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	w.p.clear()
+	defer w.p.redraw()
+
+	return w.w.Write(b)
+}
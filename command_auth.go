@@ -0,0 +1,21 @@
+package console
+
+import (
+	"crypto/subtle"
+	"errors"
+)
+
+var ErrUnauthenticated = errors.New("authentication failed")
+
+// TokenAuth accepts clients sending one of tokens, for
+// CommandListener.Authenticate.
+func TokenAuth(tokens ...string) func(token string) error {
+	return func(token string) error {
+		for _, t := range tokens {
+			if t != "" && subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				return nil
+			}
+		}
+		return errors.New("invalid token")
+	}
+}
@@ -0,0 +1,61 @@
+package console
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestAuthenticate(t *testing.T) {
+	r, l := newListener()
+	l.Authenticate = TokenAuth("secret")
+	r.RegisterCommand("asd", "", func(in Input, out Output) error {
+		fmt.Fprint(out, "asd")
+		return nil
+	})
+
+	run := func(frames ...FrameType) (string, string) {
+		server, client := net.Pipe()
+		defer client.Close()
+		go l.handleConnection(server)
+
+		for _, ft := range frames {
+			payload := []byte("secret")
+			switch ft {
+			case FrameCommand:
+				payload = CommandMessage{Name: "asd"}.ToBytes()
+			case FrameDescribe:
+				payload = []byte("asd")
+			}
+			if err := WriteFrame(client, ft, payload); err != nil {
+				t.Fatalf("WriteFrame() error = %v", err)
+			}
+		}
+		stdout := ""
+		for {
+			ft, payload, err := ReadFrame(client)
+			if err != nil {
+				t.Fatalf("ReadFrame() error = %v", err)
+			}
+			switch ft {
+			case FrameStdout:
+				stdout += string(payload)
+			case FrameExit:
+				return stdout, string(payload)
+			}
+		}
+	}
+
+	if out, exitErr := run(FrameAuth, FrameCommand); out != "asd" || exitErr != "" {
+		t.Errorf("run with token = %q, %q; want %q", out, exitErr, "asd")
+	}
+	if _, exitErr := run(FrameCommand); exitErr != ErrUnauthenticated.Error() {
+		t.Errorf("run without token error = %q; want %q", exitErr, ErrUnauthenticated)
+	}
+	if _, exitErr := run(FrameDescribe); exitErr != ErrUnauthenticated.Error() {
+		t.Errorf("describe without token error = %q; want %q", exitErr, ErrUnauthenticated)
+	}
+	if err := TokenAuth("secret")("wrong"); err == nil {
+		t.Errorf("TokenAuth()(\"wrong\") = nil; want error")
+	}
+}
@@ -0,0 +1,554 @@
+package console
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// RemoteError is the error a command exited with on the server.
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+// Result is what a command sent back. Output, log records and entries are
+// only collected when no writer or handler was given for them.
+type Result struct {
+	Stdout  []byte
+	Stderr  []byte
+	Logs    []LogRecord
+	Entries []Entry
+}
+
+type clientOptions struct {
+	tlsConfig   *tls.Config
+	token       string
+	dialTimeout time.Duration
+	timeout     time.Duration
+	retries     int
+	backoff     time.Duration
+	poolSize    int
+	keepalive   time.Duration
+
+	stdin      io.Reader
+	stdout     io.Writer
+	stderr     io.Writer
+	onLog      func(LogRecord)
+	onProgress func(ProgressUpdate)
+	onEntry    func(Entry)
+	onPrompt   func(PromptRequest) PromptReply
+}
+
+type ClientOption func(*clientOptions)
+
+func WithTLS(config *tls.Config) ClientOption {
+	return func(o *clientOptions) { o.tlsConfig = config }
+}
+
+// WithToken sends token to listeners which authenticate clients.
+func WithToken(token string) ClientOption {
+	return func(o *clientOptions) { o.token = token }
+}
+
+func WithDialTimeout(d time.Duration) ClientOption {
+	return func(o *clientOptions) { o.dialTimeout = d }
+}
+
+// WithTimeout limits how long a single command may run.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(o *clientOptions) { o.timeout = d }
+}
+
+// WithRetry retries connecting to the server up to retries times, waiting
+// backoff before the first retry and twice as long before each next one.
+// Idempotent commands are retried the same way when their connection
+// breaks before any input was sent, output streamed by the failed attempt
+// is written again.
+func WithRetry(retries int, backoff time.Duration) ClientOption {
+	return func(o *clientOptions) { o.retries, o.backoff = retries, backoff }
+}
+
+// WithPoolSize sets how many idle connections are kept for later commands.
+func WithPoolSize(n int) ClientOption {
+	return func(o *clientOptions) { o.poolSize = n }
+}
+
+// WithKeepalive pings idle connections every interval and drops those not
+// answering within it, zero disables it.
+func WithKeepalive(interval time.Duration) ClientOption {
+	return func(o *clientOptions) { o.keepalive = interval }
+}
+
+// WithStdin is read when a command reads its input.
+func WithStdin(r io.Reader) ClientOption {
+	return func(o *clientOptions) { o.stdin = r }
+}
+
+func WithStdout(w io.Writer) ClientOption {
+	return func(o *clientOptions) { o.stdout = w }
+}
+
+func WithStderr(w io.Writer) ClientOption {
+	return func(o *clientOptions) { o.stderr = w }
+}
+
+func WithLogHandler(f func(LogRecord)) ClientOption {
+	return func(o *clientOptions) { o.onLog = f }
+}
+
+func WithProgressHandler(f func(ProgressUpdate)) ClientOption {
+	return func(o *clientOptions) { o.onProgress = f }
+}
+
+func WithEntryHandler(f func(Entry)) ClientOption {
+	return func(o *clientOptions) { o.onEntry = f }
+}
+
+// WithPromptHandler answers the prompts of commands, without it every
+// prompt fails.
+func WithPromptHandler(f func(PromptRequest) PromptReply) ClientOption {
+	return func(o *clientOptions) { o.onPrompt = f }
+}
+
+const (
+	defaultPoolSize  = 2
+	defaultKeepalive = 30 * time.Second
+)
+
+// Client runs commands on a CommandListener. Connections are reused by
+// later commands, at most poolSize of them are kept while idle.
+type Client struct {
+	address string
+	opts    clientOptions
+
+	mu         sync.Mutex
+	idle       []net.Conn
+	idempotent map[string]bool
+	closed     bool
+	stop       chan struct{}
+}
+
+// Dial connects to the listener at address, so that the first command
+// does not wait for it and connection errors show early.
+func Dial(ctx context.Context, address string, opts ...ClientOption) (*Client, error) {
+	c := &Client{
+		address:    address,
+		opts:       clientOptions{poolSize: defaultPoolSize, keepalive: defaultKeepalive},
+		idempotent: make(map[string]bool),
+		stop:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(&c.opts)
+	}
+
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.put(conn)
+	if c.opts.keepalive > 0 {
+		go c.keepalive()
+	}
+	return c, nil
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	close(c.stop)
+	var errs []error
+	for _, conn := range c.idle {
+		errs = append(errs, conn.Close())
+	}
+	c.idle = nil
+	return errors.Join(errs...)
+}
+
+// errServerClosed is returned when the server closed the connection
+// before reading the frames sent on it.
+var errServerClosed = errors.New("connection closed by the server")
+
+// Describe returns the description of the command name, the error wraps
+// ErrUnknownCommand when the server has no such command.
+func (c *Client) Describe(ctx context.Context, name string) (CommandDescription, error) {
+	conn, reused, err := c.take(ctx)
+	if err != nil {
+		return CommandDescription{}, err
+	}
+	d, responded, err := c.describeOn(ctx, conn, name)
+	if err != nil && reused && !responded && ctx.Err() == nil {
+		// the server closed the pooled connection, e.g. after its
+		// IdleTimeout, the description is asked for on a new one
+		if conn, err = c.connect(ctx); err != nil {
+			return d, err
+		}
+		d, _, err = c.describeOn(ctx, conn, name)
+	}
+
+	if err == nil {
+		c.mu.Lock()
+		c.idempotent[name] = d.Idempotent
+		c.idempotent[d.Name] = d.Idempotent
+		c.mu.Unlock()
+	}
+	return d, err
+}
+
+// describeOn asks for the description of name on conn and reports whether
+// the server responded at all.
+func (c *Client) describeOn(ctx context.Context, conn net.Conn, name string) (CommandDescription, bool, error) {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	d, responded, err := describe(conn, name)
+	if err != nil && !errors.Is(err, ErrUnknownCommand) {
+		conn.Close()
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return d, responded, err
+	}
+	c.put(conn)
+	return d, responded, err
+}
+
+// Run runs msg on the server and waits for it to exit. The error is a
+// *RemoteError when the command failed on the server. Commands the server
+// declared idempotent, learned by Describe, are retried as set by
+// WithRetry. Any command is sent once more on a new connection when the
+// pooled connection could not take it or the server closed it without
+// reading it, e.g. after its IdleTimeout.
+func (c *Client) Run(ctx context.Context, msg CommandMessage) (Result, error) {
+	if c.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.timeout)
+		defer cancel()
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, retry, err := c.runOnce(ctx, msg)
+		if err == nil || !retry || attempt >= c.opts.retries || !c.isIdempotent(msg.Name) {
+			return res, err
+		}
+		select {
+		case <-time.After(c.opts.backoff << attempt):
+		case <-ctx.Done():
+			return res, ctx.Err()
+		}
+	}
+}
+
+// runOnce runs msg on a pooled or new connection. It reports whether the
+// command may run again, because the connection broke before any input
+// was sent.
+func (c *Client) runOnce(ctx context.Context, msg CommandMessage) (Result, bool, error) {
+	conn, reused, err := c.take(ctx)
+	if err != nil {
+		return Result{}, false, err
+	}
+	res, unread, retry, err := c.runOn(ctx, conn, msg)
+	if err != nil && reused && unread && ctx.Err() == nil {
+		// the server closed the pooled connection, e.g. after its
+		// IdleTimeout, without running the command, which runs on a new one
+		if conn, err = c.connect(ctx); err != nil {
+			return res, false, err
+		}
+		res, _, retry, err = c.runOn(ctx, conn, msg)
+	}
+	return res, retry, err
+}
+
+// runOn runs msg on conn, reporting whether the server surely did not read
+// the command and whether the command may run again.
+func (c *Client) runOn(ctx context.Context, conn net.Conn, msg CommandMessage) (Result, bool, bool, error) {
+	var res Result
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	inputSent, sent, err := c.run(conn, msg, &res)
+	unread := !sent || errors.Is(err, errServerClosed)
+	var remoteErr *RemoteError
+	switch {
+	case ctx.Err() != nil:
+		conn.Close()
+		return res, unread, false, ctx.Err()
+	case err == nil || errors.As(err, &remoteErr):
+		c.put(conn)
+		return res, unread, false, err
+	}
+	conn.Close()
+	return res, unread, !inputSent, err
+}
+
+func (c *Client) isIdempotent(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.idempotent[name]
+}
+
+// take returns an idle connection, or a new one, and whether it was used
+// before.
+func (c *Client) take(ctx context.Context) (net.Conn, bool, error) {
+	c.mu.Lock()
+	var conn net.Conn
+	if n := len(c.idle); n > 0 {
+		conn, c.idle = c.idle[n-1], c.idle[:n-1]
+	}
+	c.mu.Unlock()
+
+	if conn != nil {
+		return conn, true, nil
+	}
+	conn, err := c.connect(ctx)
+	return conn, false, err
+}
+
+// put returns conn to the pool, it is closed when the pool is full.
+func (c *Client) put(conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed || len(c.idle) >= c.opts.poolSize {
+		conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+func (c *Client) keepalive() {
+	ticker := time.NewTicker(c.opts.keepalive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.ping()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// ping checks the idle connections, dropping those which do not answer,
+// e.g. because the server closed them.
+func (c *Client) ping() {
+	c.mu.Lock()
+	conns := c.idle
+	c.idle = nil
+	c.mu.Unlock()
+
+	for _, conn := range conns {
+		if err := ping(conn, c.opts.keepalive); err != nil {
+			conn.Close()
+			continue
+		}
+		c.put(conn)
+	}
+}
+
+func ping(conn net.Conn, timeout time.Duration) error {
+	conn.SetDeadline(time.Now().Add(timeout))
+	defer conn.SetDeadline(time.Time{})
+
+	if err := WriteFrame(conn, FramePing, nil); err != nil {
+		return err
+	}
+	t, _, err := ReadFrame(conn)
+	if err == nil && t != FramePong {
+		err = fmt.Errorf("unexpected frame %s", t)
+	}
+	return err
+}
+
+func (c *Client) connect(ctx context.Context) (net.Conn, error) {
+	for attempt := 0; ; attempt++ {
+		conn, err := c.dial(ctx)
+		if err == nil || attempt >= c.opts.retries {
+			return conn, err
+		}
+		select {
+		case <-time.After(c.opts.backoff << attempt):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	if c.opts.dialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.dialTimeout)
+		defer cancel()
+	}
+
+	var conn net.Conn
+	var err error
+	if c.opts.tlsConfig != nil {
+		d := &tls.Dialer{Config: c.opts.tlsConfig}
+		conn, err = d.DialContext(ctx, "tcp", c.address)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", c.address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.opts.token != "" {
+		if err := WriteFrame(conn, FrameAuth, []byte(c.opts.token)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// describe asks for the description of name and reports whether the
+// server responded.
+func describe(conn net.Conn, name string) (CommandDescription, bool, error) {
+	if err := WriteFrame(conn, FrameDescribe, []byte(name)); err != nil {
+		return CommandDescription{}, false, err
+	}
+	t, payload, err := ReadFrame(conn)
+	switch {
+	case err != nil:
+		return CommandDescription{}, false, err
+	case t == FrameClose:
+		return CommandDescription{}, false, errServerClosed
+	case t == FrameExit:
+		return CommandDescription{}, true, &RemoteError{Message: string(payload)}
+	case t != FrameDescription:
+		return CommandDescription{}, true, fmt.Errorf("unexpected frame %s", t)
+	case len(payload) == 0:
+		return CommandDescription{}, true, fmt.Errorf("%w %q", ErrUnknownCommand, name)
+	}
+	d, err := CommandDescriptionFromBytes(payload)
+	return d, true, err
+}
+
+// run sends msg over conn and serves the frames of the server until the
+// command exits. stdin is only read when the server asks for data. It
+// reports whether any input was sent to the command and whether the
+// command was sent at all.
+func (c *Client) run(conn net.Conn, msg CommandMessage, res *Result) (inputSent, sent bool, err error) {
+	if err := WriteFrame(conn, FrameCommand, msg.ToBytes()); err != nil {
+		return false, false, err
+	}
+
+	eof := c.opts.stdin == nil
+	for {
+		t, payload, err := ReadFrame(conn)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return inputSent, true, io.ErrUnexpectedEOF
+			}
+			return inputSent, true, err
+		}
+
+		switch t {
+		case FrameStdout:
+			err = writeOrCollect(c.opts.stdout, &res.Stdout, payload)
+		case FrameStderr:
+			err = writeOrCollect(c.opts.stderr, &res.Stderr, payload)
+		case FrameLog:
+			var rec LogRecord
+			if rec, err = LogRecordFromBytes(payload); err != nil {
+				err = malformedFrame(t, err)
+				break
+			}
+			if c.opts.onLog != nil {
+				c.opts.onLog(rec)
+			} else {
+				res.Logs = append(res.Logs, rec)
+			}
+		case FrameProgress:
+			var u ProgressUpdate
+			if u, err = ProgressUpdateFromBytes(payload); err != nil {
+				err = malformedFrame(t, err)
+			} else if c.opts.onProgress != nil {
+				c.opts.onProgress(u)
+			}
+		case FrameEntry:
+			var e Entry
+			if e, err = EntryFromBytes(payload); err != nil {
+				err = malformedFrame(t, err)
+				break
+			}
+			if c.opts.onEntry != nil {
+				c.opts.onEntry(e)
+			} else {
+				res.Entries = append(res.Entries, e)
+			}
+		case FrameDataRequest:
+			if eof {
+				err = WriteFrame(conn, FrameDataEnd, nil)
+			} else {
+				inputSent = true
+				eof, err = c.sendData(conn, DataRequestSize(payload))
+			}
+		case FramePrompt:
+			reply := PromptReply{Err: "prompts are not supported by this client"}
+			if c.opts.onPrompt != nil {
+				var req PromptRequest
+				if req, err = PromptRequestFromBytes(payload); err != nil {
+					err = malformedFrame(t, err)
+					break
+				}
+				inputSent = true
+				reply = c.opts.onPrompt(req)
+			}
+			err = WriteFrame(conn, FramePromptReply, reply.ToBytes())
+		case FrameClose:
+			return inputSent, true, errServerClosed
+		case FrameExit:
+			if len(payload) > 0 {
+				return inputSent, true, &RemoteError{Message: string(payload)}
+			}
+			return inputSent, true, nil
+		}
+		if err != nil {
+			return inputSent, true, err
+		}
+	}
+}
+
+func malformedFrame(t FrameType, err error) error {
+	return fmt.Errorf("malformed %s frame: %w", t, err)
+}
+
+func writeOrCollect(w io.Writer, collected *[]byte, b []byte) error {
+	if w == nil {
+		*collected = append(*collected, b...)
+		return nil
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func (c *Client) sendData(conn net.Conn, size int) (eof bool, err error) {
+	buf := make([]byte, size)
+	for {
+		n, err := c.opts.stdin.Read(buf)
+		if n > 0 {
+			return errors.Is(err, io.EOF), WriteFrame(conn, FrameData, buf[:n])
+		}
+		if err != nil {
+			return true, WriteFrame(conn, FrameDataEnd, nil)
+		}
+	}
+}
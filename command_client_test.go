@@ -0,0 +1,247 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serve runs l on a free local port until the test ends and returns its
+// address and the number of connections accepted so far.
+func serve(t *testing.T, l *CommandListener) (string, func() int32) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			go l.handleConnection(conn)
+		}
+	}()
+	return ln.Addr().String(), accepted.Load
+}
+
+func TestClient(t *testing.T) {
+	r, l := newListener()
+	l.Authenticate = TokenAuth("secret")
+
+	r.RegisterCommand("upper", "", func(in Input, out Output) error {
+		b, err := io.ReadAll(in)
+		if err != nil {
+			return err
+		}
+		name, err := in.Prompt("Name", "")
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(out, strings.ToUpper(string(b))+name)
+		out.Logger().Infof("done")
+		out.KeyValue("length", len(b))
+		return nil
+	})
+	r.RegisterCommand("fail", "", func(in Input, out Output) error {
+		return errors.New("failed")
+	})
+	r.RegisterCommand("sleep", "", func(in Input, out Output) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	addr, _ := serve(t, l)
+	ctx := context.Background()
+
+	c, err := Dial(ctx, addr,
+		WithToken("secret"),
+		WithStdin(strings.NewReader("abc")),
+		WithPromptHandler(func(p PromptRequest) PromptReply { return PromptReply{Value: "!"} }))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+
+	if d, err := c.Describe(ctx, "upper"); err != nil || d.Name != "upper" {
+		t.Errorf("Describe(\"upper\") = %+v, %v; want upper", d, err)
+	}
+	if _, err := c.Describe(ctx, "nope"); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("Describe(\"nope\") error = %v; want %v", err, ErrUnknownCommand)
+	}
+
+	res, err := c.Run(ctx, CommandMessage{Name: "upper"})
+	if err != nil {
+		t.Fatalf("Run(\"upper\") error = %v", err)
+	}
+	if string(res.Stdout) != "ABC!" || len(res.Logs) != 1 || len(res.Entries) != 1 {
+		t.Errorf("Run(\"upper\") = %+v; want output, a log record and an entry", res)
+	}
+
+	var remoteErr *RemoteError
+	if _, err := c.Run(ctx, CommandMessage{Name: "fail"}); !errors.As(err, &remoteErr) || remoteErr.Message != "failed" {
+		t.Errorf("Run(\"fail\") error = %v; want remote error %q", err, "failed")
+	}
+
+	timed, err := Dial(ctx, addr, WithToken("secret"), WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer timed.Close()
+	if _, err := timed.Run(ctx, CommandMessage{Name: "sleep"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run(\"sleep\") error = %v; want %v", err, context.DeadlineExceeded)
+	}
+
+	if _, err := Dial(ctx, "localhost:1", WithRetry(2, time.Millisecond)); err == nil {
+		t.Errorf("Dial() to a closed port error = nil")
+	}
+	unauthenticated, _ := Dial(ctx, addr)
+	defer unauthenticated.Close()
+	if _, err := unauthenticated.Run(ctx, CommandMessage{Name: "upper"}); !errors.As(err, &remoteErr) {
+		t.Errorf("Run() without token error = %v; want remote error", err)
+	}
+}
+
+func TestClientPool(t *testing.T) {
+	r, l := newListener()
+	l.IdleTimeout = 50 * time.Millisecond
+
+	onExec := func(in Input, out Output) error {
+		fmt.Fprint(out, "ok")
+		return nil
+	}
+	r.RegisterCommand("once", "", onExec)
+	cmd, _ := r.RegisterCommand("again", "", onExec)
+	cmd.Idempotent()
+	addr, accepted := serve(t, l)
+	ctx := context.Background()
+
+	c, err := Dial(ctx, addr, WithKeepalive(0), WithRetry(2, time.Millisecond))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		if res, err := c.Run(ctx, CommandMessage{Name: "once"}); err != nil || string(res.Stdout) != "ok" {
+			t.Fatalf("Run(\"once\") = %q, %v; want %q", res.Stdout, err, "ok")
+		}
+	}
+	if n := accepted(); n != 1 {
+		t.Errorf("accepted %d connections for 3 commands; want 1", n)
+	}
+
+	// the server drops the pooled connection before any command was sent
+	// on it, every command reconnects without retries
+	d, err := Dial(ctx, addr, WithKeepalive(0))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer d.Close()
+	time.Sleep(100 * time.Millisecond)
+	if desc, err := d.Describe(ctx, "again"); err != nil || !desc.Idempotent {
+		t.Errorf("Describe(\"again\") on a dropped connection = %+v, %v; want idempotent", desc, err)
+	}
+	for _, name := range []string{"again", "once"} {
+		time.Sleep(100 * time.Millisecond)
+		if res, err := d.Run(ctx, CommandMessage{Name: name}); err != nil || string(res.Stdout) != "ok" {
+			t.Errorf("Run(%q) on a dropped connection = %q, %v; want %q", name, res.Stdout, err, "ok")
+		}
+	}
+
+	// pinged connections stay open, or are dropped when they break
+	k, err := Dial(ctx, addr, WithKeepalive(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer k.Close()
+	time.Sleep(100 * time.Millisecond)
+	if _, err := k.Run(ctx, CommandMessage{Name: "once"}); err != nil {
+		t.Errorf("Run() after keepalive error = %v", err)
+	}
+}
+
+func TestClientBrokenConnection(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer ln.Close()
+
+	// the server reads the command and breaks the connection before any
+	// response, like a server which crashed while running it
+	var received atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if t, _, err := ReadFrame(conn); err == nil && t == FrameCommand {
+					received.Add(1)
+				}
+			}()
+		}
+	}()
+
+	ctx := context.Background()
+	c, err := Dial(ctx, ln.Addr().String(), WithKeepalive(0))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Run(ctx, CommandMessage{Name: "asd"}); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Run() on a broken connection error = %v; want %v", err, io.ErrUnexpectedEOF)
+	}
+	if n := received.Load(); n != 1 {
+		t.Errorf("server received the command %d times; want 1", n)
+	}
+}
+
+func TestClientManyCommands(t *testing.T) {
+	r, l := newListener()
+	r.RegisterCommand("ok", "", func(in Input, out Output) error {
+		fmt.Fprint(out, "ok")
+		return nil
+	})
+	addr, _ := serve(t, l)
+
+	// the next command is sent right after the exit of the previous one,
+	// it must not be taken for a frame of the command which exited
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			c, err := Dial(context.Background(), addr, WithPoolSize(1), WithKeepalive(0), WithTimeout(5*time.Second))
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer c.Close()
+			for j := 0; j < 500; j++ {
+				if res, err := c.Run(context.Background(), CommandMessage{Name: "ok"}); err != nil || string(res.Stdout) != "ok" {
+					errs <- fmt.Errorf("run %d = %q, %w", j, res.Stdout, err)
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	}
+}
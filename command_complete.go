@@ -0,0 +1,90 @@
+package console
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const completeCommand = "__complete"
+
+// complete returns the candidates for the last of args, the words typed
+// after the name of the client. It completes command names and aliases
+// and the flags of the command they resolve to.
+func (r *Registry) complete(args []string) []string {
+	current := ""
+	if len(args) > 0 {
+		current, args = args[len(args)-1], args[:len(args)-1]
+	}
+
+	var path []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		path, args = append(path, args[0]), args[1:]
+	}
+	cmd, err := r.lookup(strings.Join(path, " "))
+	if err != nil {
+		return nil
+	}
+
+	flags := cmd.flags()
+	var candidates []string
+	switch {
+	case strings.HasPrefix(current, "-") && strings.Contains(current, "="):
+		name, _, _ := strings.Cut(current, "=")
+		if info, ok := lookupFlag(strings.TrimLeft(name, "-"), flags); ok {
+			for _, candidate := range valueCandidates(info, current[len(name)+1:]) {
+				candidates = append(candidates, name+"="+candidate)
+			}
+		}
+	case strings.HasPrefix(current, "-"):
+		for name, info := range flags {
+			candidates = append(candidates, "--"+name)
+			if isBoolFlag(info) {
+				candidates = append(candidates, "--no-"+name)
+			}
+			if info.short != 0 {
+				candidates = append(candidates, fmt.Sprintf("-%c", info.short))
+			}
+		}
+	case len(args) > 0:
+		// a flag value, or a word after the flags, which is never a command
+		prev := args[len(args)-1]
+		if !strings.HasPrefix(prev, "-") || strings.Contains(prev, "=") {
+			return nil
+		}
+		if info, ok := lookupFlag(strings.TrimLeft(prev, "-"), flags); ok {
+			candidates = valueCandidates(info, current)
+		}
+	default:
+		for _, sub := range cmd.subcommands {
+			candidates = append(candidates, sub.name)
+			candidates = append(candidates, sub.aliases...)
+		}
+	}
+
+	var matching []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			matching = append(matching, candidate)
+		}
+	}
+	sort.Strings(matching)
+	return matching
+}
+
+func lookupFlag(key string, flagsInfo map[string]commonFlagInfo) (commonFlagInfo, bool) {
+	if info, ok := flagsInfo[key]; ok {
+		return info, true
+	}
+	if name, ok := shortFlagName(key, flagsInfo); ok {
+		return flagsInfo[name], true
+	}
+	return commonFlagInfo{}, false
+}
+
+func valueCandidates(info commonFlagInfo, prefix string) []string {
+	if custom, ok := info.valueData.(customValueData); ok {
+		return custom.complete(prefix)
+	}
+	return info.choices
+}
@@ -0,0 +1,318 @@
+package console
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type flagConstraints struct {
+	min, max             *float64
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minCount, maxCount   *int
+
+	// durations shows min and max, which are nanoseconds, as durations
+	durations bool
+}
+
+type flagRuleKind int
+
+const (
+	ruleMutuallyExclusive flagRuleKind = iota + 1
+	ruleAtLeastOneOf
+	ruleRequires
+)
+
+// flagRule constrains several flags of a command. For ruleRequires the
+// first name is the flag which requires all the others.
+type flagRule struct {
+	kind  flagRuleKind
+	names []string
+}
+
+// updateFlag changes the declaration of a flag of the command, which has
+// to be declared before.
+func (c *commonCommandInfo) updateFlag(name, what string, update func(*commonFlagInfo)) *commonCommandInfo {
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+
+	info, ok := c.flagsInfo[name]
+	if !ok {
+		panic(fmt.Sprintf("console: %s for undeclared flag %q", what, name))
+	}
+	update(&info)
+	c.flagsInfo[name] = info
+
+	return c
+}
+
+// mustHaveType panics unless the type of the flag is one of the types the
+// constraint what applies to, so it is not silently ignored.
+func mustHaveType(name, what string, info *commonFlagInfo, ok func(typ string) bool) {
+	if typ := info.valueData.Type(); !ok(typ) {
+		panic(fmt.Sprintf("console: %s for flag %q of type %s", what, name, typ))
+	}
+}
+
+func isNumericType(typ string) bool {
+	switch strings.TrimPrefix(typ, "[]") {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint64",
+		"float32", "float64", "console.ByteSize", "time.Duration":
+		return true
+	}
+	return false
+}
+
+func isDurationType(typ string) bool {
+	return strings.TrimPrefix(typ, "[]") == "time.Duration"
+}
+
+func isMultiValueType(typ string) bool {
+	return strings.HasPrefix(typ, "[]") || typ == "map[string]string"
+}
+
+func (c *commonCommandInfo) addRule(rule flagRule) *commonCommandInfo {
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+
+	seen := make(map[string]bool, len(rule.names))
+	for _, name := range rule.names {
+		if !c.declares(name) {
+			panic(fmt.Sprintf("console: rule %q for undeclared flag %q", rule, name))
+		}
+		seen[name] = true
+	}
+	// a rule constrains flags to each other
+	if len(seen) < 2 {
+		panic(fmt.Sprintf("console: rule %q needs at least two different flags", rule))
+	}
+	c.rules = append(c.rules, rule)
+	return c
+}
+
+// declares reports whether the command or one of its groups declares the
+// flag.
+func (c *commonCommandInfo) declares(name string) bool {
+	for ; c != nil; c = c.parent {
+		if _, ok := c.flagsInfo[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Min rejects numeric values of the flag lower than min. The bounds of
+// durations are nanoseconds, e.g. Min("timeout", float64(time.Second)).
+func (c *commonCommandInfo) Min(name string, min float64) Command {
+	return c.updateFlag(name, "minimum", func(info *commonFlagInfo) {
+		mustHaveType(name, "minimum", info, isNumericType)
+		info.constraints.min = &min
+		info.constraints.durations = isDurationType(info.valueData.Type())
+	})
+}
+
+// Max rejects numeric values of the flag greater than max. The bounds of
+// durations are nanoseconds, e.g. Max("timeout", float64(time.Minute)).
+func (c *commonCommandInfo) Max(name string, max float64) Command {
+	return c.updateFlag(name, "maximum", func(info *commonFlagInfo) {
+		mustHaveType(name, "maximum", info, isNumericType)
+		info.constraints.max = &max
+		info.constraints.durations = isDurationType(info.valueData.Type())
+	})
+}
+
+// MinLength rejects values of the flag shorter than n characters, it
+// applies to strings and flags declared with a FlagValue.
+func (c *commonCommandInfo) MinLength(name string, n int) Command {
+	return c.updateFlag(name, "minimum length", func(info *commonFlagInfo) {
+		mustBeText(name, "minimum length", info)
+		info.constraints.minLength = &n
+	})
+}
+
+// MaxLength rejects values of the flag longer than n characters, it
+// applies to strings and flags declared with a FlagValue.
+func (c *commonCommandInfo) MaxLength(name string, n int) Command {
+	return c.updateFlag(name, "maximum length", func(info *commonFlagInfo) {
+		mustBeText(name, "maximum length", info)
+		info.constraints.maxLength = &n
+	})
+}
+
+func mustBeText(name, what string, info *commonFlagInfo) {
+	if _, ok := info.valueData.(customValueData); ok {
+		return
+	}
+	mustHaveType(name, what, info, func(typ string) bool {
+		return strings.TrimPrefix(typ, "[]") == "string"
+	})
+}
+
+// Pattern rejects values of the flag not matching the regular expression.
+func (c *commonCommandInfo) Pattern(name, pattern string) Command {
+	re := regexp.MustCompile(pattern)
+	return c.updateFlag(name, "pattern", func(info *commonFlagInfo) {
+		info.constraints.pattern = re
+	})
+}
+
+// MinCount requires the slice or map flag to be given at least n values.
+func (c *commonCommandInfo) MinCount(name string, n int) Command {
+	return c.updateFlag(name, "minimum count", func(info *commonFlagInfo) {
+		mustHaveType(name, "minimum count", info, isMultiValueType)
+		info.constraints.minCount = &n
+	})
+}
+
+// MaxCount rejects more than n values of the slice or map flag.
+func (c *commonCommandInfo) MaxCount(name string, n int) Command {
+	return c.updateFlag(name, "maximum count", func(info *commonFlagInfo) {
+		mustHaveType(name, "maximum count", info, isMultiValueType)
+		info.constraints.maxCount = &n
+	})
+}
+
+// MutuallyExclusive rejects using more than one of the flags at once.
+func (c *commonCommandInfo) MutuallyExclusive(names ...string) Command {
+	return c.addRule(flagRule{kind: ruleMutuallyExclusive, names: names})
+}
+
+// AtLeastOneOf requires at least one of the flags to be used.
+func (c *commonCommandInfo) AtLeastOneOf(names ...string) Command {
+	return c.addRule(flagRule{kind: ruleAtLeastOneOf, names: names})
+}
+
+// Requires makes the flag name valid only together with the required flags.
+func (c *commonCommandInfo) Requires(name string, required ...string) Command {
+	return c.addRule(flagRule{kind: ruleRequires, names: append([]string{name}, required...)})
+}
+
+// check tests a single value of the flag, shown is the value as it may
+// appear in errors.
+func (fc flagConstraints) check(name, value, shown string, parsed any) []error {
+	var errs []error
+	if fc.min != nil || fc.max != nil {
+		if n, ok := toFloat(parsed); ok {
+			if fc.min != nil && n < *fc.min {
+				errs = append(errs, fmt.Errorf("flag --%s must be at least %s, got %s", name, fc.bound(*fc.min), shown))
+			}
+			if fc.max != nil && n > *fc.max {
+				errs = append(errs, fmt.Errorf("flag --%s must be at most %s, got %s", name, fc.bound(*fc.max), shown))
+			}
+		}
+	}
+	length := len([]rune(value))
+	if fc.minLength != nil && length < *fc.minLength {
+		errs = append(errs, fmt.Errorf("flag --%s must be at least %d characters long", name, *fc.minLength))
+	}
+	if fc.maxLength != nil && length > *fc.maxLength {
+		errs = append(errs, fmt.Errorf("flag --%s must be at most %d characters long", name, *fc.maxLength))
+	}
+	if fc.pattern != nil && !fc.pattern.MatchString(value) {
+		errs = append(errs, fmt.Errorf("flag --%s must match %s, got %q", name, fc.pattern, shown))
+	}
+	return errs
+}
+
+func (fc flagConstraints) checkCount(name string, count int) []error {
+	var errs []error
+	if fc.minCount != nil && count < *fc.minCount {
+		errs = append(errs, fmt.Errorf("flag --%s needs at least %d values, got %d", name, *fc.minCount, count))
+	}
+	if fc.maxCount != nil && count > *fc.maxCount {
+		errs = append(errs, fmt.Errorf("flag --%s accepts at most %d values, got %d", name, *fc.maxCount, count))
+	}
+	return errs
+}
+
+// String describes the constraints for the help of the flag.
+func (fc flagConstraints) String() string {
+	var parts []string
+	if fc.min != nil {
+		parts = append(parts, "min "+fc.bound(*fc.min))
+	}
+	if fc.max != nil {
+		parts = append(parts, "max "+fc.bound(*fc.max))
+	}
+	if fc.minLength != nil {
+		parts = append(parts, fmt.Sprintf("min length %d", *fc.minLength))
+	}
+	if fc.maxLength != nil {
+		parts = append(parts, fmt.Sprintf("max length %d", *fc.maxLength))
+	}
+	if fc.pattern != nil {
+		parts = append(parts, fmt.Sprintf("matching %s", fc.pattern))
+	}
+	if fc.minCount != nil {
+		parts = append(parts, fmt.Sprintf("min %d values", *fc.minCount))
+	}
+	if fc.maxCount != nil {
+		parts = append(parts, fmt.Sprintf("max %d values", *fc.maxCount))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// bound formats the min or max of the flag.
+func (fc flagConstraints) bound(v float64) string {
+	if fc.durations {
+		return time.Duration(v).String()
+	}
+	return fmt.Sprint(v)
+}
+
+func (r flagRule) check(flags map[string][]string) error {
+	var set []string
+	for _, name := range r.names {
+		if _, ok := flags[name]; ok {
+			set = append(set, name)
+		}
+	}
+
+	switch r.kind {
+	case ruleMutuallyExclusive:
+		if len(set) > 1 {
+			return fmt.Errorf("flags %s cannot be used together", flagList(set))
+		}
+	case ruleAtLeastOneOf:
+		if len(set) == 0 {
+			return fmt.Errorf("at least one of the flags %s is required", flagList(r.names))
+		}
+	case ruleRequires:
+		if _, ok := flags[r.names[0]]; !ok {
+			return nil
+		}
+		var missing []string
+		for _, name := range r.names[1:] {
+			if _, ok := flags[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("flag --%s requires %s", r.names[0], flagList(missing))
+		}
+	}
+	return nil
+}
+
+// String describes the rule for the help of the command.
+func (r flagRule) String() string {
+	switch r.kind {
+	case ruleMutuallyExclusive:
+		return "only one of " + flagList(r.names)
+	case ruleAtLeastOneOf:
+		return "at least one of " + flagList(r.names)
+	case ruleRequires:
+		return fmt.Sprintf("--%s requires %s", r.names[0], flagList(r.names[1:]))
+	}
+	return ""
+}
+
+func flagList(names []string) string {
+	flags := make([]string, len(names))
+	for i, name := range names {
+		flags[i] = "--" + name
+	}
+	return strings.Join(flags, ", ")
+}
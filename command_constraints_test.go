@@ -0,0 +1,111 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlagConstraints(t *testing.T) {
+	r, l := newListener()
+
+	cmd, _ := r.RegisterCommand("asd", "", func(in Input, out Output) error {
+		fmt.Fprint(out, "ok")
+		return nil
+	})
+	cmd.OptionalInt("count", "", 1).Min("count", 1).Max("count", 10).
+		OptionalString("name", "", "asd").MinLength("name", 2).Pattern("name", "^[a-z]+$").
+		OptionalSliceString("tag", "", nil).MaxCount("tag", 2).
+		OptionalBool("all", "", false).
+		OptionalDuration("timeout", "", time.Second).Min("timeout", float64(time.Second)).
+		RequiredString("region", "").
+		MutuallyExclusive("all", "tag").
+		Requires("count", "name")
+
+	tests := []struct {
+		flags    map[string][]string
+		expected []string
+	}{
+		{map[string][]string{"region": {"eu"}}, nil},
+		{map[string][]string{}, []string{"missing required flag --region"}},
+		{
+			map[string][]string{"region": {"eu"}, "count": {"0"}, "name": {"A"}},
+			[]string{
+				"flag --count must be at least 1, got 0",
+				"flag --name must be at least 2 characters long",
+				"flag --name must match ^[a-z]+$, got \"A\"",
+			},
+		},
+		{map[string][]string{"region": {"eu"}, "count": {"x"}, "name": {"asd"}}, []string{`invalid value "x" for flag --count: strconv.Atoi: parsing "x": invalid syntax`}},
+		{map[string][]string{"region": {"eu"}, "count": {"5"}}, []string{"flag --count requires --name"}},
+		{map[string][]string{"region": {"eu"}, "timeout": {"500ms"}}, []string{"flag --timeout must be at least 1s, got 500ms"}},
+		{map[string][]string{"region": {"eu"}, "since": {"-2h"}, "2h": nil}, []string{"unknown flag --2h", "unknown flag --since"}},
+		{
+			map[string][]string{"region": nil, "all": nil, "tag": {"a", "b", "c"}},
+			[]string{
+				"flag --region needs a value",
+				"flag --tag accepts at most 2 values, got 3",
+				"flags --all, --tag cannot be used together",
+			},
+		},
+	}
+	for _, test := range tests {
+		out, exitErr := runCommand(t, l, CommandMessage{Name: "asd", Flags: test.flags})
+		if test.expected == nil {
+			if out != "ok" || exitErr != "" {
+				t.Errorf("runCommand(%v) = %q, %q; want %q", test.flags, out, exitErr, "ok")
+			}
+			continue
+		}
+		if expected := strings.Join(test.expected, "\n"); exitErr != expected || out != "" {
+			t.Errorf("runCommand(%v) error = %q; want %q", test.flags, exitErr, expected)
+		}
+	}
+}
+
+func TestUnenforceableConstraints(t *testing.T) {
+	tests := []struct {
+		name    string
+		declare func(cmd Command)
+	}{
+		{"min of a string", func(cmd Command) { cmd.Min("name", 1) }},
+		{"max of a time", func(cmd Command) { cmd.Max("at", 1) }},
+		{"min length of an int", func(cmd Command) { cmd.MinLength("count", 1) }},
+		{"max length of a duration", func(cmd Command) { cmd.MaxLength("timeout", 1) }},
+		{"min count of a scalar", func(cmd Command) { cmd.MinCount("name", 1) }},
+		{"max count of a scalar", func(cmd Command) { cmd.MaxCount("count", 1) }},
+		{"mutually exclusive undeclared flag", func(cmd Command) { cmd.MutuallyExclusive("al", "tag") }},
+		{"at least one of undeclared flag", func(cmd Command) { cmd.AtLeastOneOf("name", "nme") }},
+		{"requires undeclared flag", func(cmd Command) { cmd.Requires("count", "nam") }},
+		{"mutually exclusive single flag", func(cmd Command) { cmd.MutuallyExclusive("all") }},
+		{"at least one of no flags", func(cmd Command) { cmd.AtLeastOneOf() }},
+		{"requires nothing", func(cmd Command) { cmd.Requires("count") }},
+		{"at least one of the same flag", func(cmd Command) { cmd.AtLeastOneOf("name", "name") }},
+	}
+	for _, test := range tests {
+		r := NewRegistry()
+		group, _ := r.RegisterGroup("grp", "")
+		group.OptionalString("region", "", "eu")
+		cmd, _ := group.RegisterCommand("asd", "", func(in Input, out Output) error { return nil })
+		cmd.OptionalString("name", "", "").
+			OptionalInt("count", "", 1).
+			OptionalDuration("timeout", "", 0).
+			OptionalTime("at", "", time.Time{}).
+			OptionalSliceString("tag", "", nil).
+			OptionalBool("all", "", false)
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("declaring the %s did not panic", test.name)
+				}
+			}()
+			test.declare(cmd)
+		}()
+
+		// the same constraints are fine on flags they apply to
+		cmd.Min("timeout", 0).Max("count", 3).MinLength("name", 1).MaxCount("tag", 2).
+			MutuallyExclusive("all", "tag").Requires("name", "region")
+	}
+}
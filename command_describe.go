@@ -0,0 +1,129 @@
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// CommandDescription is what a client learns about a command before running
+// it, e.g. to apply defaults from the environment or from its config file.
+type CommandDescription struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	Aliases     []string             `json:"aliases,omitempty"`
+	Group       bool                 `json:"group,omitempty"`
+	Idempotent  bool                 `json:"idempotent,omitempty"`
+	Flags       []FlagDescription    `json:"flags,omitempty"`
+	Commands    []CommandDescription `json:"commands,omitempty"`
+}
+
+type FlagDescription struct {
+	Name        string   `json:"name"`
+	Short       string   `json:"short,omitempty"`
+	Type        string   `json:"type"`
+	Description string   `json:"description,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Choices     []string `json:"choices,omitempty"`
+	Env         string   `json:"env,omitempty"`
+	Secret      bool     `json:"secret,omitempty"`
+	FromFile    bool     `json:"from_file,omitempty"`
+}
+
+func CommandDescriptionFromBytes(data []byte) (CommandDescription, error) {
+	var d CommandDescription
+	err := json.Unmarshal(data, &d)
+	return d, err
+}
+
+func (d CommandDescription) ToBytes() ([]byte, error) {
+	return json.Marshal(d)
+}
+
+// Flag returns the flag named name or having the short name name.
+func (d CommandDescription) Flag(name string) (FlagDescription, bool) {
+	for _, f := range d.Flags {
+		if f.Name == name || (f.Short != "" && f.Short == name) {
+			return f, true
+		}
+	}
+	return FlagDescription{}, false
+}
+
+// Describe returns the command at the space separated path name together
+// with all its subcommands, an empty name describes the whole registry.
+func (r *Registry) Describe(name string) (CommandDescription, error) {
+	rc, err := r.lookup(name)
+	if err != nil {
+		return CommandDescription{}, err
+	}
+
+	d := CommandDescription{
+		Name:        strings.Join(rc.path, " "),
+		Description: rc.info.Description,
+		Aliases:     rc.info.aliases,
+		Group:       rc.isGroup(),
+		Idempotent:  rc.info.idempotent,
+	}
+	flags := rc.flags()
+	for _, key := range sortedKeys(flags) {
+		d.Flags = append(d.Flags, describeFlag(key, flags[key]))
+	}
+	for _, sub := range rc.subcommands {
+		// a subcommand may be unregistered in the meantime
+		if sd, err := r.Describe(d.Name + " " + sub.name); err == nil {
+			d.Commands = append(d.Commands, sd)
+		}
+	}
+	return d, nil
+}
+
+func describeFlag(name string, info commonFlagInfo) FlagDescription {
+	f := FlagDescription{
+		Name:        name,
+		Type:        info.valueData.Type(),
+		Description: info.description,
+		Required:    info.isRequired,
+		Choices:     info.choices,
+		Env:         info.env,
+		Secret:      info.secret,
+		FromFile:    info.fromFile,
+	}
+	if info.short != 0 {
+		f.Short = string(info.short)
+	}
+	if v := info.valueData.Get(); v != nil && !info.secret {
+		f.Default = formatDefault(v)
+	}
+	return f
+}
+
+// formatDefault formats v the way it would be sent by a client, slices as
+// comma separated values.
+func formatDefault(v any) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return fmt.Sprint(v)
+	}
+	values := make([]string, rv.Len())
+	for i := range values {
+		values[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return strings.Join(values, ",")
+}
+
+// describeReply is the payload of FrameDescription, empty for an unknown
+// command so that the client runs it and gets the usual error.
+func (c *CommandListener) describeReply(name string) []byte {
+	d, err := c.registry().Describe(name)
+	if err != nil {
+		return nil
+	}
+	b, err := d.ToBytes()
+	if err != nil {
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,73 @@
+package console
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	r, l := newListener()
+
+	cache, _ := r.RegisterGroup("cache", "Cache commands")
+	cache.OptionalString("region", "Region", "eu").Short("region", 'r').Env("region", "CACHE_REGION")
+	flush, _ := cache.RegisterCommand("flush", "Flushes the cache", func(in Input, out Output) error {
+		var region string
+		in.ParseString(&region, "region")
+		fmt.Fprint(out, region)
+		return nil
+	})
+	flush.OptionalSliceString("key", "", []string{"a", "b"}).RequiredString("token", "").Secret("token")
+
+	d, err := r.Describe("")
+	if err != nil {
+		t.Fatalf("Describe(\"\") error = %v", err)
+	}
+	if len(d.Commands) != 1 || len(d.Commands[0].Commands) != 1 || d.Commands[0].Commands[0].Name != "cache flush" {
+		t.Fatalf("Describe(\"\") = %+v; want cache flush nested in cache", d)
+	}
+	flushDesc := d.Commands[0].Commands[0]
+	tests := []struct {
+		name     string
+		expected FlagDescription
+	}{
+		{"r", FlagDescription{Name: "region", Short: "r", Type: "string", Description: "Region", Default: "eu", Env: "CACHE_REGION"}},
+		{"key", FlagDescription{Name: "key", Type: "[]string", Default: "a,b"}},
+		{"token", FlagDescription{Name: "token", Type: "string", Required: true, Secret: true}},
+	}
+	for _, test := range tests {
+		if f, ok := flushDesc.Flag(test.name); !ok || !reflect.DeepEqual(f, test.expected) {
+			t.Errorf("Flag(%q) = %+v; want %+v", test.name, f, test.expected)
+		}
+	}
+	if _, err := r.Describe("cache flsh"); err == nil {
+		t.Errorf("Describe(\"cache flsh\") error = nil; want unknown command")
+	}
+
+	// the description is sent before the command on the same connection
+	server, client := net.Pipe()
+	defer client.Close()
+	go l.handleConnection(server)
+	WriteFrame(client, FrameDescribe, []byte("cache flush"))
+	ft, payload, err := ReadFrame(client)
+	if err != nil || ft != FrameDescription {
+		t.Fatalf("ReadFrame() = %v, %v; want description", ft, err)
+	}
+	if got, err := CommandDescriptionFromBytes(payload); err != nil || got.Name != "cache flush" {
+		t.Errorf("description = %+v, %v; want cache flush", got, err)
+	}
+	WriteFrame(client, FrameCommand, CommandMessage{Name: "cache flush", Flags: map[string][]string{"token": {"x"}}}.ToBytes())
+	for {
+		ft, payload, err := ReadFrame(client)
+		if err != nil {
+			t.Fatalf("ReadFrame() error = %v", err)
+		}
+		if ft == FrameStdout && string(payload) != "eu" {
+			t.Errorf("stdout = %q; want %q", payload, "eu")
+		}
+		if ft == FrameExit {
+			break
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// resolveFlags maps the flags sent by the client to the declared flags of
+// the command, e.g. -r to --region and --no-verbose to --verbose=false.
+// A flag sent without values, like a bare --verbose, is kept as a key
+// without values.
+func resolveFlags(flags map[string][]string, flagsInfo map[string]commonFlagInfo) map[string][]string {
+	resolved := make(map[string][]string, len(flags))
+	for key, values := range flags {
+		if _, ok := flagsInfo[key]; !ok {
+			if name, ok := shortFlagName(key, flagsInfo); ok {
+				key = name
+			} else if name, ok := negatedFlagName(key, flagsInfo); ok {
+				key, values = name, negate(values)
+			}
+		}
+		resolved[key] = append(resolved[key], values...)
+	}
+	return resolved
+}
+
+func isBoolFlag(info commonFlagInfo) bool {
+	t := info.valueData.Type()
+	return t == "bool" || t == "[]bool"
+}
+
+func negatedFlagName(key string, flagsInfo map[string]commonFlagInfo) (string, bool) {
+	name, ok := strings.CutPrefix(key, "no-")
+	if !ok {
+		return "", false
+	}
+	info, ok := flagsInfo[name]
+	return name, ok && isBoolFlag(info)
+}
+
+func negate(values []string) []string {
+	if len(values) == 0 {
+		return []string{"false"}
+	}
+
+	negated := make([]string, len(values))
+	for i, v := range values {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			negated[i] = v
+			continue
+		}
+		negated[i] = strconv.FormatBool(!b)
+	}
+	return negated
+}
+
+func shortFlagName(key string, flagsInfo map[string]commonFlagInfo) (string, bool) {
+	short, size := utf8.DecodeRuneInString(key)
+	if size == 0 || size != len(key) {
+		return "", false
+	}
+	for _, name := range sortedKeys(flagsInfo) {
+		if flagsInfo[name].short == short {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// validateFlags checks the resolved flags against their declarations and
+// the rules of the command before it runs, reporting every problem at once.
+func validateFlags(flags map[string][]string, flagsInfo map[string]commonFlagInfo, rules []flagRule) error {
+	var errs []error
+	for _, name := range sortedKeys(flagsInfo) {
+		info := flagsInfo[name]
+		values, ok := flags[name]
+		switch {
+		case !ok:
+			if info.isRequired {
+				errs = append(errs, fmt.Errorf("missing required flag --%s", name))
+			}
+			continue
+		case len(values) == 0 && !isBoolFlag(info):
+			errs = append(errs, fmt.Errorf("flag --%s needs a value", name))
+			continue
+		}
+		errs = append(errs, validateValues(name, values, info)...)
+	}
+	for _, name := range sortedKeys(flags) {
+		if _, ok := flagsInfo[name]; !ok {
+			errs = append(errs, fmt.Errorf("unknown flag --%s", name))
+		}
+	}
+	for _, rule := range rules {
+		if err := rule.check(flags); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateValues(name string, values []string, info commonFlagInfo) []error {
+	errs := info.constraints.checkCount(name, len(values))
+	parse, ok := valueParser(info.valueData.Type())
+	if custom, isCustom := info.valueData.(customValueData); isCustom {
+		parse, ok = custom.parse, true
+	}
+	for _, v := range values {
+		shown := v
+		if info.secret {
+			shown = redacted
+		}
+		if len(info.choices) > 0 && !isChoice(v, info.choices) {
+			errs = append(errs, fmt.Errorf("invalid value %q for flag --%s, expected one of %s", shown, name, strings.Join(info.choices, ", ")))
+			continue
+		}
+
+		var parsed any = v
+		if ok {
+			var err error
+			if parsed, err = parse(v); err != nil {
+				if info.secret {
+					// parse errors usually quote the value
+					err = errors.New("invalid syntax")
+				}
+				errs = append(errs, fmt.Errorf("invalid value %q for flag --%s: %w", shown, name, err))
+				continue
+			}
+		}
+		errs = append(errs, info.constraints.check(name, v, shown, parsed)...)
+	}
+	return errs
+}
+
+func isChoice(value string, choices []string) bool {
+	for _, choice := range choices {
+		if value == choice {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,86 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBoolFlags(t *testing.T) {
+	r, l := newListener()
+
+	cmd, _ := r.RegisterCommand("asd", "", func(in Input, out Output) error {
+		var asd bool
+		var asd2 []bool
+		in.ParseBool(&asd, "asd")
+		in.ParseBoolSlice(&asd2, "asd2")
+		fmt.Fprint(out, asd, asd2)
+		return nil
+	})
+	cmd.OptionalBool("asd", "", true).Short("asd", 'a').OptionalSliceBool("asd2", "", []bool{true})
+
+	tests := []struct {
+		flags    map[string][]string
+		expected string
+	}{
+		{map[string][]string{}, "true [true]"},
+		{map[string][]string{"asd": nil}, "true [true]"},
+		{map[string][]string{"a": {"false"}}, "false [true]"},
+		{map[string][]string{"no-asd": nil}, "false [true]"},
+		{map[string][]string{"no-asd": {"false"}}, "true [true]"},
+		{map[string][]string{"asd": {"false"}, "asd2": {"false", "true"}}, "false [false true]"},
+		{map[string][]string{"no-asd2": nil}, "true [false]"},
+		{map[string][]string{"asd2": nil}, "true [true]"},
+	}
+	for _, test := range tests {
+		if out, exitErr := runCommand(t, l, CommandMessage{Name: "asd", Flags: test.flags}); out != test.expected {
+			t.Errorf("runCommand(%v) = %q, %q; want %q", test.flags, out, exitErr, test.expected)
+		}
+	}
+}
+
+func TestEnumFlags(t *testing.T) {
+	r, l := newListener()
+
+	cmd, _ := r.RegisterCommand("asd", "", func(in Input, out Output) error {
+		var level string
+		in.ParseString(&level, "level")
+		fmt.Fprint(out, level)
+		return nil
+	})
+	cmd.OptionalEnum("level", "Level", "info", "debug", "info", "warn").Short("level", 'l')
+
+	if out, _ := runCommand(t, l, CommandMessage{Name: "asd"}); out != "info" {
+		t.Errorf("runCommand(\"asd\") = %q; want %q", out, "info")
+	}
+	if out, _ := runCommand(t, l, CommandMessage{Name: "asd", Flags: map[string][]string{"l": {"warn"}}}); out != "warn" {
+		t.Errorf("runCommand(\"asd -l warn\") = %q; want %q", out, "warn")
+	}
+	_, exitErr := runCommand(t, l, CommandMessage{Name: "asd", Flags: map[string][]string{"level": {"trace"}}})
+	if expected := `invalid value "trace" for flag --level, expected one of debug, info, warn`; exitErr != expected {
+		t.Errorf("runCommand(\"asd --level trace\") error = %q; want %q", exitErr, expected)
+	}
+	if out, _ := runCommand(t, l, CommandMessage{Name: "asd", Flags: map[string][]string{"help": nil}}); !strings.Contains(out, "--level debug|info|warn") {
+		t.Errorf("runCommand(\"asd --help\") = %q; want choices of --level", out)
+	}
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"asd", "--level", ""}, []string{"debug", "info", "warn"}},
+		{[]string{"asd", "-l", "w"}, []string{"warn"}},
+		{[]string{"asd", "--level=d"}, []string{"--level=debug"}},
+	}
+	for _, test := range tests {
+		if got := r.complete(test.args); !stringsEqual(got, test.expected) {
+			t.Errorf("complete(%q) = %q; want %q", test.args, got, test.expected)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("OptionalEnum() with the default \"fast\" out of its choices did not panic")
+		}
+	}()
+	cmd.OptionalEnum("mode", "", "fast", "a", "b")
+}
@@ -0,0 +1,81 @@
+package console
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+type FrameType byte
+
+// client -> server
+const (
+	FrameCommand FrameType = iota + 1
+	FrameData
+	FrameDataEnd
+	FramePromptReply
+	FrameDescribe
+	FrameAuth
+	FramePing
+)
+
+// server -> client
+const (
+	FrameStdout FrameType = iota + 0x40
+	FrameDataRequest
+	FrameExit
+	FramePrompt
+	FrameStderr
+	FrameLog
+	FrameEntry
+	FrameProgress
+	FrameDescription
+	FramePong
+	// FrameClose is sent before the server closes an idle connection, no
+	// frame sent after it is read
+	FrameClose
+)
+
+const (
+	frameHeaderSize = 5
+	maxFrameSize    = 16 << 20
+	maxDataChunk    = 32 << 10
+)
+
+func WriteFrame(w io.Writer, t FrameType, payload []byte) error {
+	if len(payload) > maxFrameSize {
+		return fmt.Errorf("frame too large: %d bytes", len(payload))
+	}
+	b := make([]byte, frameHeaderSize, frameHeaderSize+len(payload))
+	b[0] = byte(t)
+	binary.BigEndian.PutUint32(b[1:], uint32(len(payload)))
+	b = append(b, payload...)
+	_, err := w.Write(b)
+	return err
+}
+
+func ReadFrame(r io.Reader) (FrameType, []byte, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(header[1:])
+	if n > maxFrameSize {
+		return 0, nil, fmt.Errorf("frame too large: %d bytes", n)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return FrameType(header[0]), payload, nil
+}
+
+func DataRequestSize(payload []byte) int {
+	if len(payload) < 4 {
+		return maxDataChunk
+	}
+	return int(binary.BigEndian.Uint32(payload))
+}
@@ -0,0 +1,64 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestFrame(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteFrame(&b, FrameStdout, []byte("asd")); err != nil {
+		t.Fatalf("WriteFrame() error = %v", err)
+	}
+	expected := []byte{byte(FrameStdout), 0, 0, 0, 3, 'a', 's', 'd'}
+	if !byteEqual(b.Bytes(), expected) {
+		t.Errorf("WriteFrame() = %v; want %v", b.Bytes(), expected)
+	}
+
+	ft, payload, err := ReadFrame(&b)
+	if err != nil {
+		t.Fatalf("ReadFrame() error = %v", err)
+	}
+	if ft != FrameStdout || string(payload) != "asd" {
+		t.Errorf("ReadFrame() = %v, %q; want %v, %q", ft, payload, FrameStdout, "asd")
+	}
+
+	if _, _, err := ReadFrame(bytes.NewReader(expected[:6])); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadFrame() on truncated frame error = %v; want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestInputStream(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	s := newSession(newConnection(server, discardLogger), discardLogger)
+	go s.readLoop()
+
+	go func() {
+		chunks := []string{"asd", "asd2"}
+		for {
+			ft, _, err := ReadFrame(client)
+			if err != nil || ft != FrameDataRequest {
+				return
+			}
+			if len(chunks) == 0 {
+				WriteFrame(client, FrameDataEnd, nil)
+				continue
+			}
+			WriteFrame(client, FrameData, []byte(chunks[0]))
+			chunks = chunks[1:]
+		}
+	}()
+
+	data, err := io.ReadAll(&inputStream{s: s})
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(data) != "asdasd2" {
+		t.Errorf("ReadAll() = %q; want %q", data, "asdasd2")
+	}
+}
@@ -0,0 +1,109 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const helpCommand = "help"
+
+// helpPath returns the command "help [command...]" asks about. The
+// built-in help is only used when no command named help is registered.
+func helpPath(name string) (string, bool) {
+	path := strings.Fields(name)
+	if len(path) == 0 || path[0] != helpCommand {
+		return "", false
+	}
+	return strings.Join(path[1:], " "), true
+}
+
+func writeHelp(w io.Writer, c resolvedCommand) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+
+	usage := strings.Join(c.path, " ")
+	switch {
+	case len(c.path) == 0:
+		usage = "<command>"
+	case c.isGroup():
+		usage += " <command>"
+	}
+	fmt.Fprintf(tw, "Usage: %s [flags]\n", usage)
+	if c.info.Description != "" {
+		fmt.Fprintf(tw, "\n%s\n", c.info.Description)
+	}
+	if len(c.info.aliases) > 0 {
+		fmt.Fprintf(tw, "\nAliases: %s\n", strings.Join(c.info.aliases, ", "))
+	}
+
+	if len(c.subcommands) > 0 {
+		fmt.Fprintf(tw, "\nCommands:\n")
+		for _, sub := range c.subcommands {
+			name := sub.name
+			if len(sub.aliases) > 0 {
+				name += " (" + strings.Join(sub.aliases, ", ") + ")"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\n", name, sub.description)
+		}
+	}
+	writeFlagsHelp(tw, "Flags", c.info.flagsInfo)
+	writeFlagsHelp(tw, "Inherited flags", c.inherited)
+	if len(c.rules) > 0 {
+		fmt.Fprintf(tw, "\nRules:\n")
+		for _, rule := range c.rules {
+			fmt.Fprintf(tw, "  %s\n", rule)
+		}
+	}
+
+	return tw.Flush()
+}
+
+func writeFlagsHelp(w io.Writer, title string, flags map[string]commonFlagInfo) {
+	if len(flags) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%s:\n", title)
+	for _, name := range sortedKeys(flags) {
+		info := flags[name]
+		short := "    "
+		if info.short != 0 {
+			short = fmt.Sprintf("-%c, ", info.short)
+		}
+		long := "--" + name
+		if isBoolFlag(info) {
+			long = "--[no-]" + name
+		}
+		valueType := info.valueData.Type()
+		if len(info.choices) > 0 {
+			valueType = strings.Join(info.choices, "|")
+		}
+		fmt.Fprintf(w, "  %s%s %s\t%s%s\n", short, long, valueType, info.description, flagHelpSuffix(info))
+	}
+}
+
+func flagHelpSuffix(info commonFlagInfo) string {
+	suffix := ""
+	if info.isRequired {
+		suffix = " (required)"
+	} else if v := info.valueData.Get(); v != nil {
+		if info.secret {
+			v = redacted
+		}
+		suffix = fmt.Sprintf(" (default %v)", v)
+	}
+	if info.secret {
+		suffix += " (secret)"
+	}
+	if info.fromFile {
+		suffix += " (@file)"
+	}
+	if info.env != "" {
+		suffix += " (env " + info.env + ")"
+	}
+	if constraints := info.constraints.String(); constraints != "" {
+		suffix += " (" + constraints + ")"
+	}
+	return suffix
+}
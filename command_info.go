@@ -2,10 +2,38 @@ package console
 
 import (
 	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 )
 
 type Command interface {
+	RegisterCommand(name, description string, callback func(Input, Output) error) (Command, error)
+	RegisterGroup(name, description string) (Command, error)
+	ReplaceCommand(name, description string, callback func(Input, Output) error) Command
+	Replace(cmd Command) Command
+	UnregisterCommand(name string) bool
+	Alias(aliases ...string) Command
+	Idempotent() Command
+	Short(name string, short rune) Command
+	Secret(names ...string) Command
+	Env(name, variable string) Command
+	FromFile(names ...string) Command
+
+	Min(name string, min float64) Command
+	Max(name string, max float64) Command
+	MinLength(name string, n int) Command
+	MaxLength(name string, n int) Command
+	Pattern(name, pattern string) Command
+	MinCount(name string, n int) Command
+	MaxCount(name string, n int) Command
+	MutuallyExclusive(names ...string) Command
+	AtLeastOneOf(names ...string) Command
+	Requires(name string, required ...string) Command
+
 	RequiredInt(name, description string) Command
 	RequiredInt64(name, description string) Command
 	RequiredUint(name, description string) Command
@@ -14,6 +42,19 @@ type Command interface {
 	RequiredString(name, description string) Command
 	RequiredFloat64(name, description string) Command
 	RequiredDuration(name, description string) Command
+	RequiredInt8(name, description string) Command
+	RequiredInt16(name, description string) Command
+	RequiredInt32(name, description string) Command
+	RequiredFloat32(name, description string) Command
+	RequiredTime(name, description string) Command
+	RequiredIP(name, description string) Command
+	RequiredPrefix(name, description string) Command
+	RequiredURL(name, description string) Command
+	RequiredByteSize(name, description string) Command
+	RequiredRegexp(name, description string) Command
+	RequiredStringMap(name, description string) Command
+	RequiredEnum(name, description string, choices ...string) Command
+	RequiredVar(name, description string, value FlagValue) Command
 	RequiredSliceInt(name, description string) Command
 	RequiredSliceInt64(name, description string) Command
 	RequiredSliceUint(name, description string) Command
@@ -31,6 +72,19 @@ type Command interface {
 	OptionalString(name, description string, value string) Command
 	OptionalFloat64(name, description string, value float64) Command
 	OptionalDuration(name, description string, value time.Duration) Command
+	OptionalInt8(name, description string, value int8) Command
+	OptionalInt16(name, description string, value int16) Command
+	OptionalInt32(name, description string, value int32) Command
+	OptionalFloat32(name, description string, value float32) Command
+	OptionalTime(name, description string, value time.Time) Command
+	OptionalIP(name, description string, value net.IP) Command
+	OptionalPrefix(name, description string, value netip.Prefix) Command
+	OptionalURL(name, description string, value *url.URL) Command
+	OptionalByteSize(name, description string, value ByteSize) Command
+	OptionalRegexp(name, description string, value *regexp.Regexp) Command
+	OptionalStringMap(name, description string, value map[string]string) Command
+	OptionalEnum(name, description string, value string, choices ...string) Command
+	OptionalVar(name, description string, value FlagValue) Command
 	OptionalSliceInt(name, description string, value []int) Command
 	OptionalSliceInt64(name, description string, value []int64) Command
 	OptionalSliceUint(name, description string, value []uint) Command
@@ -41,37 +95,35 @@ type Command interface {
 	OptionalSliceDuration(name, description string, value []time.Duration) Command
 }
 
-var (
-	commandInfoMap = make(map[string]commonCommandInfo)
-)
-
-func RegisterCommand(name, description string, callback func(Input, Output) error) *commonCommandInfo {
-
-	c := commonCommandInfo{
-		Name:            name,
-		Description:     description,
-		ExecuteCallback: callback,
-		flagsInfo:       make(map[string]commonFlagInfo),
-	}
-	commandInfoMap[c.Name] = c
-
-	return &c
-}
-
 type commonCommandInfo struct {
 	Name            string
 	Description     string
 	ExecuteCallback func(Input, Output) error
 	flagsInfo       map[string]commonFlagInfo
+	rules           []flagRule
+	aliases         []string
+	idempotent      bool
+	children        map[string]*commonCommandInfo
+	parent          *commonCommandInfo
+	registry        *Registry
 }
 
 type commonFlagInfo struct {
 	isRequired  bool
+	short       rune
 	description string
 	valueData   Value
+	choices     []string
+	constraints flagConstraints
+	secret      bool
+	env         string
+	fromFile    bool
 }
 
 func (c *commonCommandInfo) requiredFlagInfo(name, description string, valueData TypeOnlyValue) *commonCommandInfo {
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+
 	c.flagsInfo[name] = commonFlagInfo{
 		isRequired:  true,
 		description: description,
@@ -112,6 +164,56 @@ func (c *commonCommandInfo) RequiredDuration(name, description string) Command {
 	return c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "time.Duration"})
 }
 
+func (c *commonCommandInfo) RequiredInt8(name, description string) Command {
+	return c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "int8"})
+}
+
+func (c *commonCommandInfo) RequiredInt16(name, description string) Command {
+	return c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "int16"})
+}
+
+func (c *commonCommandInfo) RequiredInt32(name, description string) Command {
+	return c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "int32"})
+}
+
+func (c *commonCommandInfo) RequiredFloat32(name, description string) Command {
+	return c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "float32"})
+}
+
+func (c *commonCommandInfo) RequiredTime(name, description string) Command {
+	return c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "time.Time"})
+}
+
+func (c *commonCommandInfo) RequiredIP(name, description string) Command {
+	return c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "net.IP"})
+}
+
+func (c *commonCommandInfo) RequiredPrefix(name, description string) Command {
+	return c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "netip.Prefix"})
+}
+
+func (c *commonCommandInfo) RequiredURL(name, description string) Command {
+	return c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "*url.URL"})
+}
+
+func (c *commonCommandInfo) RequiredByteSize(name, description string) Command {
+	return c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "console.ByteSize"})
+}
+
+func (c *commonCommandInfo) RequiredRegexp(name, description string) Command {
+	return c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "*regexp.Regexp"})
+}
+
+func (c *commonCommandInfo) RequiredStringMap(name, description string) Command {
+	return c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "map[string]string"})
+}
+
+// RequiredEnum declares a string flag which only accepts one of choices.
+func (c *commonCommandInfo) RequiredEnum(name, description string, choices ...string) Command {
+	c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "string"})
+	return c.setChoices(name, choices)
+}
+
 func (c *commonCommandInfo) RequiredSliceInt(name, description string) Command {
 	return c.requiredFlagInfo(name, description, typeOnlyValueData{dataType: "[]int"})
 }
@@ -145,6 +247,9 @@ func (c *commonCommandInfo) RequiredSliceDuration(name, description string) Comm
 }
 
 func (c *commonCommandInfo) optionalFlagInfo(name, description string, valueData Value) *commonCommandInfo {
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+
 	c.flagsInfo[name] = commonFlagInfo{
 		isRequired:  false,
 		description: description,
@@ -186,6 +291,65 @@ func (c *commonCommandInfo) OptionalDuration(name, description string, value tim
 	return c.optionalFlagInfo(name, description, fullValueData{dataType: "time.Duration", dataValue: value})
 }
 
+func (c *commonCommandInfo) OptionalInt8(name, description string, value int8) Command {
+	return c.optionalFlagInfo(name, description, fullValueData{dataType: "int8", dataValue: value})
+}
+
+func (c *commonCommandInfo) OptionalInt16(name, description string, value int16) Command {
+	return c.optionalFlagInfo(name, description, fullValueData{dataType: "int16", dataValue: value})
+}
+
+func (c *commonCommandInfo) OptionalInt32(name, description string, value int32) Command {
+	return c.optionalFlagInfo(name, description, fullValueData{dataType: "int32", dataValue: value})
+}
+
+func (c *commonCommandInfo) OptionalFloat32(name, description string, value float32) Command {
+	return c.optionalFlagInfo(name, description, fullValueData{dataType: "float32", dataValue: value})
+}
+
+func (c *commonCommandInfo) OptionalTime(name, description string, value time.Time) Command {
+	return c.optionalFlagInfo(name, description, fullValueData{dataType: "time.Time", dataValue: value})
+}
+
+func (c *commonCommandInfo) OptionalIP(name, description string, value net.IP) Command {
+	return c.optionalFlagInfo(name, description, fullValueData{dataType: "net.IP", dataValue: value})
+}
+
+func (c *commonCommandInfo) OptionalPrefix(name, description string, value netip.Prefix) Command {
+	return c.optionalFlagInfo(name, description, fullValueData{dataType: "netip.Prefix", dataValue: value})
+}
+
+func (c *commonCommandInfo) OptionalURL(name, description string, value *url.URL) Command {
+	return c.optionalFlagInfo(name, description, fullValueData{dataType: "*url.URL", dataValue: value})
+}
+
+func (c *commonCommandInfo) OptionalByteSize(name, description string, value ByteSize) Command {
+	return c.optionalFlagInfo(name, description, fullValueData{dataType: "console.ByteSize", dataValue: value})
+}
+
+func (c *commonCommandInfo) OptionalRegexp(name, description string, value *regexp.Regexp) Command {
+	return c.optionalFlagInfo(name, description, fullValueData{dataType: "*regexp.Regexp", dataValue: value})
+}
+
+func (c *commonCommandInfo) OptionalStringMap(name, description string, value map[string]string) Command {
+	return c.optionalFlagInfo(name, description, fullValueData{dataType: "map[string]string", dataValue: value})
+}
+
+// OptionalEnum declares a string flag which only accepts one of choices.
+func (c *commonCommandInfo) OptionalEnum(name, description string, value string, choices ...string) Command {
+	c.optionalFlagInfo(name, description, fullValueData{dataType: "string", dataValue: value})
+	return c.setChoices(name, choices)
+}
+
+func (c *commonCommandInfo) setChoices(name string, choices []string) *commonCommandInfo {
+	return c.updateFlag(name, "choices", func(info *commonFlagInfo) {
+		if value, ok := info.valueData.Get().(string); ok && !isChoice(value, choices) {
+			panic(fmt.Sprintf("console: default %q of flag %q is not one of %s", value, name, strings.Join(choices, ", ")))
+		}
+		info.choices = append([]string(nil), choices...)
+	})
+}
+
 func (c *commonCommandInfo) OptionalSliceInt(name, description string, value []int) Command {
 	return c.optionalFlagInfo(name, description, fullValueData{dataType: "[]int", dataValue: value})
 }
@@ -0,0 +1,76 @@
+package console
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+// discardHandler drops every record, it is the default handler of the
+// listener so that a service embedding it gets no output it did not ask for.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+var discardLogger = slog.New(discardHandler{})
+
+func loggerOrDiscard(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return discardLogger
+	}
+	return l
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+func (t FrameType) String() string {
+	switch t {
+	case FrameCommand:
+		return "command"
+	case FrameData:
+		return "data"
+	case FrameDataEnd:
+		return "data-end"
+	case FramePromptReply:
+		return "prompt-reply"
+	case FrameDescribe:
+		return "describe"
+	case FrameAuth:
+		return "auth"
+	case FramePing:
+		return "ping"
+	case FrameStdout:
+		return "stdout"
+	case FrameDataRequest:
+		return "data-request"
+	case FrameExit:
+		return "exit"
+	case FramePrompt:
+		return "prompt"
+	case FrameStderr:
+		return "stderr"
+	case FrameLog:
+		return "log"
+	case FrameEntry:
+		return "entry"
+	case FrameProgress:
+		return "progress"
+	case FrameDescription:
+		return "description"
+	case FramePong:
+		return "pong"
+	case FrameClose:
+		return "close"
+	}
+	return "unknown"
+}
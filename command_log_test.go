@@ -0,0 +1,30 @@
+package console
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestListenerLogger(t *testing.T) {
+	r, l := newListener()
+	var buf strings.Builder
+	l.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	cmd, _ := r.RegisterCommand("login", "", func(in Input, out Output) error {
+		return errors.New("denied")
+	})
+	cmd.RequiredString("password", "").Secret("password")
+
+	runCommand(t, l, CommandMessage{Name: "login", Flags: map[string][]string{"password": {"hunter2"}}})
+	logs := buf.String()
+	for _, expected := range []string{"command=login", "request_id=", "remote=", "duration=", "error=denied", "password:[****]", "type=exit"} {
+		if !strings.Contains(logs, expected) {
+			t.Errorf("logs = %q; want %q", logs, expected)
+		}
+	}
+	if strings.Contains(logs, "hunter2") {
+		t.Errorf("logs = %q; contain the secret", logs)
+	}
+}
@@ -1,6 +1,13 @@
 package console
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// errTruncated is returned when decoding a payload which ends before the
+// values it announces, e.g. one sent by a broken or hostile peer.
+var errTruncated = errors.New("truncated payload")
 
 type CommandMessage struct {
 	Name  string
@@ -20,32 +27,60 @@ func writeStringSlice(b []byte, s []string) []byte {
 	return b
 }
 
-func readString(b []byte) (string, []byte) {
-	n := binary.BigEndian.Uint32(b) + 4
-	return string(b[4:n]), b[n:]
+func readUint32(b []byte) (uint32, []byte, error) {
+	if len(b) < 4 {
+		return 0, nil, errTruncated
+	}
+	return binary.BigEndian.Uint32(b), b[4:], nil
 }
 
-func readStringSlice(b []byte) ([]string, []byte) {
-	var n uint32
-	n, b = binary.BigEndian.Uint32(b), b[4:]
+func readString(b []byte) (string, []byte, error) {
+	n, b, err := readUint32(b)
+	if err != nil {
+		return "", nil, err
+	}
+	if uint64(n) > uint64(len(b)) {
+		return "", nil, errTruncated
+	}
+	return string(b[:n]), b[n:], nil
+}
+
+func readStringSlice(b []byte) ([]string, []byte, error) {
+	n, b, err := readUint32(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	// every string takes at least its length, checked before allocating
+	if uint64(n)*4 > uint64(len(b)) {
+		return nil, nil, errTruncated
+	}
 	s := make([]string, n)
-	for i := uint32(0); i < n; i++ {
-		s[i], b = readString(b)
+	for i := range s {
+		if s[i], b, err = readString(b); err != nil {
+			return nil, nil, err
+		}
 	}
-	return s, b
+	return s, b, nil
 }
 
-func MessageFromBytes(data []byte) CommandMessage {
+func MessageFromBytes(data []byte) (CommandMessage, error) {
 	c := CommandMessage{}
-	c.Name, data = readString(data)
+	var err error
+	if c.Name, data, err = readString(data); err != nil {
+		return CommandMessage{}, err
+	}
 	c.Flags = make(map[string][]string)
 	var name string
 	for len(data) > 0 {
-		name, data = readString(data)
-		c.Flags[name], data = readStringSlice(data)
+		if name, data, err = readString(data); err != nil {
+			return CommandMessage{}, err
+		}
+		if c.Flags[name], data, err = readStringSlice(data); err != nil {
+			return CommandMessage{}, err
+		}
 	}
 
-	return c
+	return c, nil
 }
 
 func (c CommandMessage) ToBytes() []byte {
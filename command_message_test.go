@@ -61,8 +61,10 @@ func TestWriteStringSlice(t *testing.T) {
 
 func TestReadString(t *testing.T) {
 	b := []byte{0, 0, 0, 3, 'a', 's', 'd'}
-	var s string
-	s, b = readString(b)
+	s, b, err := readString(b)
+	if err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
 	if len(b) > 0 {
 		t.Errorf("ReadString() []byte = %v; want empty", b)
 	}
@@ -74,8 +76,10 @@ func TestReadString(t *testing.T) {
 
 func TestReadStringSlice(t *testing.T) {
 	b := []byte{0, 0, 0, 2, 0, 0, 0, 3, 'a', 's', 'd', 0, 0, 0, 4, 'a', 's', 'd', '2'}
-	var s []string
-	s, b = readStringSlice(b)
+	s, b, err := readStringSlice(b)
+	if err != nil {
+		t.Fatalf("ReadStringSlice() error = %v", err)
+	}
 	if len(b) > 0 {
 		t.Errorf("ReadStringSlice() []byte = %v; want empty", b)
 	}
@@ -98,8 +102,89 @@ func TestMessage(t *testing.T) {
 		},
 	}
 	b := msg.ToBytes()
-	msg2 := MessageFromBytes(b)
-	if !msgEqual(msg, msg2) {
-		t.Errorf("MessageFromBytes() = %v; want %v", msg2, msg)
+	msg2, err := MessageFromBytes(b)
+	if err != nil || !msgEqual(msg, msg2) {
+		t.Errorf("MessageFromBytes() = %v, %v; want %v", msg2, err, msg)
+	}
+}
+
+func TestPromptRequest(t *testing.T) {
+	req := PromptRequest{
+		Kind:     PromptSelect,
+		Question: "asd?",
+		Default:  "asd2",
+		Choices:  []string{"asd", "asd2"},
+	}
+	req2, err := PromptRequestFromBytes(req.ToBytes())
+	if err != nil || req2.Kind != req.Kind || req2.Question != req.Question || req2.Default != req.Default || !stringsEqual(req2.Choices, req.Choices) {
+		t.Errorf("PromptRequestFromBytes() = %v, %v; want %v", req2, err, req)
+	}
+
+	reply := PromptReply{Value: "asd", Err: "asd2"}
+	if reply2, err := PromptReplyFromBytes(reply.ToBytes()); err != nil || reply2 != reply {
+		t.Errorf("PromptReplyFromBytes() = %v, %v; want %v", reply2, err, reply)
+	}
+}
+
+func TestLogRecord(t *testing.T) {
+	rec := LogRecord{Level: LevelWarn, Message: "asd"}
+	if rec2, err := LogRecordFromBytes(rec.ToBytes()); err != nil || rec2 != rec {
+		t.Errorf("LogRecordFromBytes() = %v, %v; want %v", rec2, err, rec)
+	}
+}
+
+func TestProgressUpdate(t *testing.T) {
+	u := ProgressUpdate{Name: "asd", Status: "asd2", Current: 3, Total: 10, ETA: 1500, Done: true}
+	if u2, err := ProgressUpdateFromBytes(u.ToBytes()); err != nil || u2 != u {
+		t.Errorf("ProgressUpdateFromBytes() = %v, %v; want %v", u2, err, u)
+	}
+}
+
+func TestTruncatedPayloads(t *testing.T) {
+	entry, _ := Entry{Kind: EntryRecord, Fields: []Field{{Key: "asd", Value: 1}}}.ToBytes()
+	decoders := map[string]struct {
+		valid  []byte
+		decode func([]byte) error
+	}{
+		"MessageFromBytes": {
+			CommandMessage{Name: "asd", Flags: map[string][]string{"asd": {"asd2"}}}.ToBytes(),
+			func(b []byte) error { _, err := MessageFromBytes(b); return err },
+		},
+		"PromptRequestFromBytes": {
+			PromptRequest{Kind: PromptSelect, Question: "asd?", Choices: []string{"asd"}}.ToBytes(),
+			func(b []byte) error { _, err := PromptRequestFromBytes(b); return err },
+		},
+		"PromptReplyFromBytes": {
+			PromptReply{Value: "asd", Err: "asd2"}.ToBytes(),
+			func(b []byte) error { _, err := PromptReplyFromBytes(b); return err },
+		},
+		"LogRecordFromBytes": {
+			LogRecord{Level: LevelInfo, Message: "asd"}.ToBytes(),
+			func(b []byte) error { _, err := LogRecordFromBytes(b); return err },
+		},
+		"ProgressUpdateFromBytes": {
+			ProgressUpdate{Name: "asd", Total: 3}.ToBytes(),
+			func(b []byte) error { _, err := ProgressUpdateFromBytes(b); return err },
+		},
+		"EntryFromBytes": {
+			entry,
+			func(b []byte) error { _, err := EntryFromBytes(b); return err },
+		},
+	}
+	for name, d := range decoders {
+		for n := 0; n < len(d.valid); n++ {
+			// the name alone is a whole message
+			if name == "MessageFromBytes" && n == 7 {
+				continue
+			}
+			if err := d.decode(d.valid[:n]); err == nil {
+				t.Errorf("%s(%v) error = nil; want error", name, d.valid[:n])
+			}
+		}
+	}
+
+	// a huge count is rejected before allocating for it
+	if _, _, err := readStringSlice([]byte{0xff, 0xff, 0xff, 0xff}); err == nil {
+		t.Errorf("readStringSlice() with a huge count error = nil; want error")
 	}
 }
@@ -1,10 +1,15 @@
 package console
 
 import (
-	"encoding/binary"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net"
 	"os"
+	"time"
 )
 
 func GetAdress() (address string) {
@@ -21,19 +26,46 @@ func ListenCommands() error {
 
 type CommandListener struct {
 	Address string
+	// Registry holds the commands of the listener, DefaultRegistry when
+	// nil.
+	Registry *Registry
+	// Logger receives a record per command and, at debug level, every
+	// frame sent or received. Nothing is logged by default.
+	Logger *slog.Logger
+	// TLSConfig makes the listener accept TLS connections only.
+	TLSConfig *tls.Config
+	// Authenticate is called with the token sent by the client before any
+	// command is described or run, e.g. TokenAuth.
+	Authenticate func(token string) error
+	// IdleTimeout closes connections on which no command was run or
+	// described for this long, also when the client pings them. Zero keeps
+	// them open until the client disconnects.
+	IdleTimeout time.Duration
 }
 
 func NewCommandListener(address string) *CommandListener {
 	return &CommandListener{
-		Address: address,
+		Address:  address,
+		Registry: DefaultRegistry,
+		Logger:   discardLogger,
 	}
 }
 
+func (c *CommandListener) registry() *Registry {
+	if c.Registry == nil {
+		return DefaultRegistry
+	}
+	return c.Registry
+}
+
 func (c *CommandListener) ListenCommands() error {
 	l, err := net.Listen("tcp", c.Address)
 	if err != nil {
 		return err
 	}
+	if c.TLSConfig != nil {
+		l = tls.NewListener(l, c.TLSConfig)
+	}
 	defer l.Close()
 
 	for {
@@ -46,31 +78,129 @@ func (c *CommandListener) ListenCommands() error {
 	}
 }
 
+// handleConnection runs the commands of a client until it disconnects.
 func (c *CommandListener) handleConnection(conn net.Conn) {
-	defer conn.Close()
+	logger := loggerOrDiscard(c.Logger).With("remote", conn.RemoteAddr().String())
+	cc := newConnection(conn, logger)
+	defer cc.close()
+
+	for {
+		msg, err := cc.readCommand(c.describeReply, c.Authenticate, c.IdleTimeout)
+		switch {
+		case errors.Is(err, io.EOF):
+			logger.Debug("connection closed")
+			return
+		case errors.Is(err, errIdleTimeout):
+			logger.Debug("closing idle connection")
+			// a client sending a command meanwhile learns it was not run
+			cc.writeFrame(FrameClose, nil)
+			return
+		case errors.Is(err, ErrUnauthenticated):
+			logger.Warn("reading command failed", "error", err)
+			// the client learns nothing but that it was not let in
+			cc.writeFrame(FrameExit, []byte(ErrUnauthenticated.Error()))
+			return
+		case err != nil:
+			logger.Warn("reading command failed", "error", err)
+			return
+		}
+
+		c.serve(cc, msg, logger.With("request_id", newRequestID(), "command", msg.Name))
+	}
+}
+
+func (c *CommandListener) serve(cc *connection, msg CommandMessage, logger *slog.Logger) {
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		logger.Debug("command received", "flags", c.redact(msg).Flags)
+	}
+
+	s := newSession(cc, logger)
+	go s.readLoop()
+
+	start := time.Now()
+	err := c.execute(s, msg)
+	if err != nil {
+		logger.Error("command failed", "duration", time.Since(start), "error", err)
+	} else {
+		logger.Info("command finished", "duration", time.Since(start))
+	}
+	if err := s.finish(err); err != nil {
+		logger.Warn("writing exit status failed", "error", err)
+	}
+}
+
+func (c *CommandListener) execute(s *session, msg CommandMessage) (err error) {
+	out := newCommandOutput(s)
 
-	buffer := make([]byte, 4)
-	_, err := conn.Read(buffer)
+	cmd, err := c.registry().lookup(msg.Name)
 	if err != nil {
-		fmt.Println("Błąd odczytu danych:", err.Error())
-		return
+		return c.executeBuiltin(out, msg, err)
+	}
+	if _, ok := msg.Flags["help"]; ok || cmd.isGroup() {
+		return writeHelp(out, cmd)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok && errors.Is(e, ErrUndeclaredFlag) {
+				err = fmt.Errorf("command %q: %w", msg.Name, e)
+				return
+			}
+			err = fmt.Errorf("command %q panicked: %v", msg.Name, r)
+		}
+	}()
+
+	flagsInfo := cmd.flags()
+	flags := resolveFlags(msg.Flags, flagsInfo)
+	prompter := &remotePrompter{s: s}
+	if err := promptSecrets(flags, flagsInfo, prompter); err != nil {
+		return err
+	}
+	if err := validateFlags(flags, flagsInfo, cmd.rules); err != nil {
+		return err
 	}
-	n := binary.BigEndian.Uint32(buffer)
 
-	fmt.Println("Received: ", n)
+	in := &commandInput{
+		FlagParser: &FlagParser{flags: flags, flagsInfo: flagsInfo, logger: s.logger},
+		Reader:     &inputStream{s: s},
+		Prompter:   prompter,
+	}
 
-	buffer = make([]byte, n)
-	_, err = conn.Read(buffer)
+	return cmd.info.ExecuteCallback(in, out)
+}
+
+// redact returns msg with the values of secret flags of its command
+// replaced, for printing.
+func (c *CommandListener) redact(msg CommandMessage) CommandMessage {
+	cmd, err := c.registry().lookup(msg.Name)
 	if err != nil {
-		fmt.Println("Błąd odczytu danych:", err.Error())
-		return
+		return msg
 	}
+	msg.Flags = redactFlags(msg.Flags, cmd.flags())
+	return msg
+}
 
-	fmt.Println("Received: ", buffer)
-	msg := MessageFromBytes(buffer)
+// executeBuiltin runs the commands the listener provides when the registry
+// has no command of that name, otherwise it returns lookupErr.
+func (c *CommandListener) executeBuiltin(out Output, msg CommandMessage, lookupErr error) error {
+	if name, ok := helpPath(msg.Name); ok {
+		cmd, err := c.registry().lookup(name)
+		if err != nil {
+			return err
+		}
+		return writeHelp(out, cmd)
+	}
 
-	in := &FlagParser{flags: msg.Flags}
+	if msg.Name == completeCommand {
+		for _, candidate := range c.registry().complete(msg.Flags["args"]) {
+			fmt.Fprintln(out, candidate)
+		}
+		return nil
+	}
 
-	commandInfoMap[msg.Name].ExecuteCallback(in, conn)
+	if msg.Name == schemaCommand {
+		return writeSchema(out, c.registry(), msg.Flags)
+	}
 
+	return lookupErr
 }
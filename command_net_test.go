@@ -0,0 +1,67 @@
+package console
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+// runCommand executes msg on l over an in-memory connection and returns
+// the stdout of the command and the error it exited with.
+func runCommand(t *testing.T, l *CommandListener, msg CommandMessage) (string, string) {
+	t.Helper()
+
+	server, client := net.Pipe()
+	served := make(chan struct{})
+	go func() {
+		l.handleConnection(server)
+		close(served)
+	}()
+	defer func() {
+		client.Close()
+		<-served
+	}()
+
+	if err := WriteFrame(client, FrameCommand, msg.ToBytes()); err != nil {
+		t.Fatalf("WriteFrame() error = %v", err)
+	}
+
+	stdout := ""
+	for {
+		ft, payload, err := ReadFrame(client)
+		if err != nil {
+			t.Fatalf("ReadFrame() error = %v", err)
+		}
+		switch ft {
+		case FrameStdout:
+			stdout += string(payload)
+		case FrameExit:
+			return stdout, string(payload)
+		}
+	}
+}
+
+// newListener returns a listener serving a new registry.
+func newListener() (*Registry, *CommandListener) {
+	r := NewRegistry()
+	l := NewCommandListener("")
+	l.Registry = r
+	return r, l
+}
+
+func TestListenerWithoutRegistry(t *testing.T) {
+	RegisterCommand("listener-without-registry", "", func(in Input, out Output) error {
+		fmt.Fprint(out, "ok")
+		return nil
+	})
+	defer UnregisterCommand("listener-without-registry")
+
+	// a listener built without NewCommandListener uses DefaultRegistry
+	l := &CommandListener{}
+	if out, exitErr := runCommand(t, l, CommandMessage{Name: "listener-without-registry"}); out != "ok" || exitErr != "" {
+		t.Errorf("runCommand() = %q, %q; want %q", out, exitErr, "ok")
+	}
+	if _, exitErr := runCommand(t, l, CommandMessage{Name: "help"}); exitErr != "" {
+		t.Errorf("runCommand(\"help\") error = %q", exitErr)
+	}
+}
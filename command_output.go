@@ -0,0 +1,180 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Output interface {
+	io.Writer
+	StructuredOutput
+
+	Stdout() io.Writer
+	Stderr() io.Writer
+	Logger() Logger
+	Progress(name string, total int64) Progress
+}
+
+type Logger interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+type LogLevel byte
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LEVEL(%d)", byte(l))
+}
+
+func ParseLogLevel(s string) (LogLevel, error) {
+	for l := LevelDebug; l <= LevelError; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+type LogRecord struct {
+	Level   LogLevel
+	Message string
+}
+
+func LogRecordFromBytes(data []byte) (LogRecord, error) {
+	if len(data) == 0 {
+		return LogRecord{}, errTruncated
+	}
+	r := LogRecord{Level: LogLevel(data[0])}
+	var err error
+	if r.Message, _, err = readString(data[1:]); err != nil {
+		return LogRecord{}, err
+	}
+
+	return r, nil
+}
+
+func (r LogRecord) ToBytes() []byte {
+	return writeString([]byte{byte(r.Level)}, r.Message)
+}
+
+type commandOutput struct {
+	s      *session
+	stdout *outputStream
+	stderr *outputStream
+	logger *remoteLogger
+}
+
+func newCommandOutput(s *session) *commandOutput {
+	return &commandOutput{
+		s:      s,
+		stdout: &outputStream{s: s, t: FrameStdout},
+		stderr: &outputStream{s: s, t: FrameStderr},
+		logger: &remoteLogger{s: s},
+	}
+}
+
+func (o *commandOutput) Write(p []byte) (int, error) {
+	return o.stdout.Write(p)
+}
+
+func (o *commandOutput) Stdout() io.Writer {
+	return o.stdout
+}
+
+func (o *commandOutput) Stderr() io.Writer {
+	return o.stderr
+}
+
+func (o *commandOutput) Logger() Logger {
+	return o.logger
+}
+
+func (o *commandOutput) Progress(name string, total int64) Progress {
+	return newRemoteProgress(o.s, name, total)
+}
+
+func (o *commandOutput) Record(fields ...Field) error {
+	return o.writeEntry(Entry{Kind: EntryRecord, Fields: fields})
+}
+
+func (o *commandOutput) Item(value any) error {
+	return o.writeEntry(Entry{Kind: EntryItem, Fields: []Field{{Value: value}}})
+}
+
+func (o *commandOutput) KeyValue(key string, value any) error {
+	return o.writeEntry(Entry{Kind: EntryKeyValue, Fields: []Field{{Key: key, Value: value}}})
+}
+
+func (o *commandOutput) writeEntry(e Entry) error {
+	b, err := e.ToBytes()
+	if err != nil {
+		return err
+	}
+	return o.s.writeFrame(FrameEntry, b)
+}
+
+type outputStream struct {
+	s *session
+	t FrameType
+}
+
+func (w *outputStream) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxDataChunk {
+			chunk = chunk[:maxDataChunk]
+		}
+		if err := w.s.writeFrame(w.t, chunk); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
+
+type remoteLogger struct {
+	s *session
+}
+
+func (l *remoteLogger) log(level LogLevel, format string, args []any) {
+	r := LogRecord{Level: level, Message: fmt.Sprintf(format, args...)}
+	l.s.writeFrame(FrameLog, r.ToBytes())
+}
+
+func (l *remoteLogger) Debugf(format string, args ...any) {
+	l.log(LevelDebug, format, args)
+}
+
+func (l *remoteLogger) Infof(format string, args ...any) {
+	l.log(LevelInfo, format, args)
+}
+
+func (l *remoteLogger) Warnf(format string, args ...any) {
+	l.log(LevelWarn, format, args)
+}
+
+func (l *remoteLogger) Errorf(format string, args ...any) {
+	l.log(LevelError, format, args)
+}
@@ -0,0 +1,132 @@
+package console
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const progressInterval = 100 * time.Millisecond
+
+type Progress interface {
+	Add(n int64)
+	Set(current int64)
+	Status(format string, args ...any)
+	Done()
+}
+
+type ProgressUpdate struct {
+	Name    string
+	Status  string
+	Current int64
+	Total   int64
+	ETA     time.Duration
+	Done    bool
+}
+
+func ProgressUpdateFromBytes(data []byte) (ProgressUpdate, error) {
+	p := ProgressUpdate{}
+	var err error
+	if p.Name, data, err = readString(data); err != nil {
+		return ProgressUpdate{}, err
+	}
+	if p.Status, data, err = readString(data); err != nil {
+		return ProgressUpdate{}, err
+	}
+	if len(data) < 25 {
+		return ProgressUpdate{}, errTruncated
+	}
+	p.Current = int64(binary.BigEndian.Uint64(data))
+	p.Total = int64(binary.BigEndian.Uint64(data[8:]))
+	p.ETA = time.Duration(binary.BigEndian.Uint64(data[16:]))
+	p.Done = data[24] == 1
+
+	return p, nil
+}
+
+func (p ProgressUpdate) ToBytes() []byte {
+	b := writeString(nil, p.Name)
+	b = writeString(b, p.Status)
+	b = binary.BigEndian.AppendUint64(b, uint64(p.Current))
+	b = binary.BigEndian.AppendUint64(b, uint64(p.Total))
+	b = binary.BigEndian.AppendUint64(b, uint64(p.ETA))
+	if p.Done {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+// remoteProgress reports a task to the client. Updates are throttled to
+// progressInterval, only status changes and completion are sent at once.
+type remoteProgress struct {
+	mu       sync.Mutex
+	s        *session
+	started  time.Time
+	lastSent time.Time
+	update   ProgressUpdate
+}
+
+func newRemoteProgress(s *session, name string, total int64) *remoteProgress {
+	p := &remoteProgress{
+		s:       s,
+		started: time.Now(),
+		update:  ProgressUpdate{Name: name, Total: total},
+	}
+	p.send(true)
+
+	return p
+}
+
+func (p *remoteProgress) Add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.update.Current += n
+	p.send(false)
+}
+
+func (p *remoteProgress) Set(current int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.update.Current = current
+	p.send(false)
+}
+
+func (p *remoteProgress) Status(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.update.Status = fmt.Sprintf(format, args...)
+	p.send(true)
+}
+
+func (p *remoteProgress) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.update.Done {
+		return
+	}
+	if p.update.Total > 0 {
+		p.update.Current = p.update.Total
+	}
+	p.update.Done = true
+	p.send(true)
+}
+
+func (p *remoteProgress) send(force bool) {
+	now := time.Now()
+	if !force && now.Sub(p.lastSent) < progressInterval {
+		return
+	}
+	p.lastSent = now
+
+	p.update.ETA = 0
+	if p.update.Current > 0 && p.update.Total > p.update.Current {
+		elapsed := now.Sub(p.started)
+		p.update.ETA = time.Duration(float64(elapsed) / float64(p.update.Current) * float64(p.update.Total-p.update.Current))
+	}
+	p.s.writeFrame(FrameProgress, p.update.ToBytes())
+}
@@ -0,0 +1,126 @@
+package console
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+)
+
+type Prompter interface {
+	Prompt(question, defaultValue string) (string, error)
+	PromptSecret(question string) (string, error)
+	Select(question string, choices []string, defaultValue string) (string, error)
+	Confirm(question string) (bool, error)
+}
+
+type PromptKind byte
+
+const (
+	PromptText PromptKind = iota + 1
+	PromptSecret
+	PromptSelect
+	PromptConfirm
+)
+
+type PromptRequest struct {
+	Kind     PromptKind
+	Question string
+	Default  string
+	Choices  []string
+}
+
+func PromptRequestFromBytes(data []byte) (PromptRequest, error) {
+	if len(data) == 0 {
+		return PromptRequest{}, errTruncated
+	}
+	p := PromptRequest{Kind: PromptKind(data[0])}
+	data = data[1:]
+	var err error
+	if p.Question, data, err = readString(data); err != nil {
+		return PromptRequest{}, err
+	}
+	if p.Default, data, err = readString(data); err != nil {
+		return PromptRequest{}, err
+	}
+	if p.Choices, _, err = readStringSlice(data); err != nil {
+		return PromptRequest{}, err
+	}
+
+	return p, nil
+}
+
+func (p PromptRequest) ToBytes() []byte {
+	b := []byte{byte(p.Kind)}
+	b = writeString(b, p.Question)
+	b = writeString(b, p.Default)
+	return writeStringSlice(b, p.Choices)
+}
+
+// PromptReply carries either the answer of the operator or the reason the
+// client could not ask, e.g. because it runs without a terminal.
+type PromptReply struct {
+	Value string
+	Err   string
+}
+
+func PromptReplyFromBytes(data []byte) (PromptReply, error) {
+	p := PromptReply{}
+	var err error
+	if p.Value, data, err = readString(data); err != nil {
+		return PromptReply{}, err
+	}
+	if p.Err, _, err = readString(data); err != nil {
+		return PromptReply{}, err
+	}
+
+	return p, nil
+}
+
+func (p PromptReply) ToBytes() []byte {
+	b := writeString(nil, p.Value)
+	return writeString(b, p.Err)
+}
+
+type remotePrompter struct {
+	mu sync.Mutex
+	s  *session
+}
+
+func (p *remotePrompter) ask(req PromptRequest) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.s.writeFrame(FramePrompt, req.ToBytes()); err != nil {
+		return "", err
+	}
+
+	select {
+	case reply := <-p.s.replies:
+		if reply.Err != "" {
+			return "", errors.New(reply.Err)
+		}
+		return reply.Value, nil
+	case <-p.s.closed:
+		return "", errSessionClosed
+	}
+}
+
+func (p *remotePrompter) Prompt(question, defaultValue string) (string, error) {
+	return p.ask(PromptRequest{Kind: PromptText, Question: question, Default: defaultValue})
+}
+
+func (p *remotePrompter) PromptSecret(question string) (string, error) {
+	return p.ask(PromptRequest{Kind: PromptSecret, Question: question})
+}
+
+func (p *remotePrompter) Select(question string, choices []string, defaultValue string) (string, error) {
+	return p.ask(PromptRequest{Kind: PromptSelect, Question: question, Default: defaultValue, Choices: choices})
+}
+
+func (p *remotePrompter) Confirm(question string) (bool, error) {
+	answer, err := p.ask(PromptRequest{Kind: PromptConfirm, Question: question, Default: "false"})
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(answer)
+}
@@ -0,0 +1,421 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrDuplicateCommand = errors.New("command already registered")
+	ErrUnknownCommand   = errors.New("unknown command")
+)
+
+// Registry owns a tree of commands and command groups. A Registry can be
+// served by any number of CommandListeners, the package-level functions
+// use DefaultRegistry.
+//
+// A Registry is safe for concurrent use, commands can be registered,
+// replaced and unregistered while listeners are serving it. A running
+// command keeps the definition it was started with.
+type Registry struct {
+	mu   sync.RWMutex
+	root *commonCommandInfo
+}
+
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	r := &Registry{}
+	r.root = r.newCommandInfo(nil, "", "", nil)
+	return r
+}
+
+func RegisterCommand(name, description string, callback func(Input, Output) error) (Command, error) {
+	return DefaultRegistry.RegisterCommand(name, description, callback)
+}
+
+func RegisterGroup(name, description string) (Command, error) {
+	return DefaultRegistry.RegisterGroup(name, description)
+}
+
+func ReplaceCommand(name, description string, callback func(Input, Output) error) Command {
+	return DefaultRegistry.ReplaceCommand(name, description, callback)
+}
+
+func Replace(cmd Command) Command {
+	return DefaultRegistry.Replace(cmd)
+}
+
+func UnregisterCommand(name string) bool {
+	return DefaultRegistry.UnregisterCommand(name)
+}
+
+// RegisterCommand adds the command and returns it for declaring its
+// flags. It fails with ErrDuplicateCommand if the name is already taken.
+func (r *Registry) RegisterCommand(name, description string, callback func(Input, Output) error) (Command, error) {
+	return r.root.RegisterCommand(name, description, callback)
+}
+
+// RegisterGroup adds a command without a callback, which holds
+// subcommands and flags shared by them. Running a group prints its help.
+func (r *Registry) RegisterGroup(name, description string) (Command, error) {
+	return r.root.RegisterGroup(name, description)
+}
+
+// ReplaceCommand adds the command, replacing the one registered under the
+// same name. Connections already running the old command are not affected.
+// The command is served as soon as it is added, flags declared on the
+// result may be missing for connections running it meanwhile, build such
+// commands with NewCommand and add them with Replace.
+func (r *Registry) ReplaceCommand(name, description string, callback func(Input, Output) error) Command {
+	return r.root.ReplaceCommand(name, description, callback)
+}
+
+// Replace adds cmd, built with NewCommand, replacing the command registered
+// under the same name. The command is added with all its flags and
+// subcommands at once, later declarations on cmd are not seen by the
+// registry, use the returned command instead.
+func (r *Registry) Replace(cmd Command) Command {
+	return r.root.Replace(cmd)
+}
+
+func (r *Registry) UnregisterCommand(name string) bool {
+	return r.root.UnregisterCommand(name)
+}
+
+func (r *Registry) newCommandInfo(parent *commonCommandInfo, name, description string, callback func(Input, Output) error) *commonCommandInfo {
+	return &commonCommandInfo{
+		Name:            name,
+		Description:     description,
+		ExecuteCallback: callback,
+		flagsInfo:       make(map[string]commonFlagInfo),
+		children:        make(map[string]*commonCommandInfo),
+		parent:          parent,
+		registry:        r,
+	}
+}
+
+func checkCommandName(name string) {
+	if name == "" || strings.ContainsAny(name, " \t\r\n") || strings.HasPrefix(name, "-") {
+		panic(fmt.Sprintf("console: invalid command name %q", name))
+	}
+}
+
+func (c *commonCommandInfo) RegisterCommand(name, description string, callback func(Input, Output) error) (Command, error) {
+	checkCommandName(name)
+
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+
+	if _, ok := c.child(name); ok {
+		return nil, fmt.Errorf("%w: %q", ErrDuplicateCommand, strings.Join(append(c.path(), name), " "))
+	}
+	return c.add(name, description, callback), nil
+}
+
+func (c *commonCommandInfo) RegisterGroup(name, description string) (Command, error) {
+	return c.RegisterCommand(name, description, nil)
+}
+
+func (c *commonCommandInfo) ReplaceCommand(name, description string, callback func(Input, Output) error) Command {
+	checkCommandName(name)
+
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+
+	return c.add(name, description, callback)
+}
+
+// NewCommand creates a command outside of any registry. Its flags and
+// subcommands are declared before it is served, Replace adds it.
+func NewCommand(name, description string, callback func(Input, Output) error) Command {
+	checkCommandName(name)
+	return NewRegistry().root.add(name, description, callback)
+}
+
+func (c *commonCommandInfo) Replace(cmd Command) Command {
+	src, ok := cmd.(*commonCommandInfo)
+	if !ok || src.parent == nil {
+		panic(fmt.Sprintf("console: cannot add %T, create commands with NewCommand", cmd))
+	}
+	if src.registry == c.registry {
+		panic(fmt.Sprintf("console: command %q is already registered", src.Name))
+	}
+
+	src.registry.mu.RLock()
+	child := src.clone(c.registry, c)
+	src.registry.mu.RUnlock()
+
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+
+	for _, alias := range child.aliases {
+		if other, ok := c.child(alias); ok && other.Name != child.Name {
+			panic(fmt.Sprintf("console: alias %q of %q is already used by %q", alias, child.Name, other.Name))
+		}
+	}
+	child.checkShorts()
+	c.children[child.Name] = child
+
+	return child
+}
+
+// clone copies c and its subcommands into the registry r.
+func (c *commonCommandInfo) clone(r *Registry, parent *commonCommandInfo) *commonCommandInfo {
+	n := *c
+	n.registry, n.parent = r, parent
+	n.flagsInfo = copyFlags(c.flagsInfo)
+	n.rules = append([]flagRule(nil), c.rules...)
+	n.aliases = append([]string(nil), c.aliases...)
+	n.children = make(map[string]*commonCommandInfo, len(c.children))
+	for name, child := range c.children {
+		n.children[name] = child.clone(r, &n)
+	}
+	return &n
+}
+
+func (c *commonCommandInfo) add(name, description string, callback func(Input, Output) error) *commonCommandInfo {
+	child := c.registry.newCommandInfo(c, name, description, callback)
+	c.children[name] = child
+
+	return child
+}
+
+func (c *commonCommandInfo) UnregisterCommand(name string) bool {
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+
+	_, ok := c.children[name]
+	delete(c.children, name)
+	return ok
+}
+
+// Alias adds other names the command can be run with.
+func (c *commonCommandInfo) Alias(aliases ...string) Command {
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+
+	if c.parent == nil {
+		panic("console: the root of a registry cannot have aliases")
+	}
+	for _, alias := range aliases {
+		checkCommandName(alias)
+		if other, ok := c.parent.child(alias); ok && other != c {
+			panic(fmt.Sprintf("console: alias %q of %q is already used by %q", alias, c.Name, other.Name))
+		}
+		c.aliases = append(c.aliases, alias)
+	}
+
+	return c
+}
+
+// Idempotent marks a command which can safely run again, clients retry it
+// when the connection breaks while it runs.
+func (c *commonCommandInfo) Idempotent() Command {
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+
+	c.idempotent = true
+	return c
+}
+
+// Short adds a single letter name to a declared flag, e.g. -r for --region.
+// The short name cannot be used by another flag of the command, of its
+// groups or of its subcommands.
+func (c *commonCommandInfo) Short(name string, short rune) Command {
+	return c.updateFlag(name, "short name", func(info *commonFlagInfo) {
+		other, ok := c.shortOwner(name, short)
+		if !ok {
+			other, ok = c.descendantShortOwner(name, short)
+		}
+		if ok {
+			panic(fmt.Sprintf("console: short name %q of flag %q is already used by %q", short, name, other))
+		}
+		info.short = short
+	})
+}
+
+// shortOwner returns the flag other than name using short on the command
+// or one of its groups.
+func (c *commonCommandInfo) shortOwner(name string, short rune) (string, bool) {
+	for ; c != nil; c = c.parent {
+		for other, info := range c.flagsInfo {
+			if other != name && info.short == short {
+				return other, true
+			}
+		}
+	}
+	return "", false
+}
+
+// descendantShortOwner returns the flag other than name using short on one
+// of the subcommands of the command.
+func (c *commonCommandInfo) descendantShortOwner(name string, short rune) (string, bool) {
+	for _, child := range c.children {
+		for other, info := range child.flagsInfo {
+			if other != name && info.short == short {
+				return other, true
+			}
+		}
+		if other, ok := child.descendantShortOwner(name, short); ok {
+			return other, true
+		}
+	}
+	return "", false
+}
+
+// checkShorts panics when a short name of a flag of c or its subcommands
+// is used by another flag of their groups, e.g. after c was added by
+// Replace.
+func (c *commonCommandInfo) checkShorts() {
+	for name, info := range c.flagsInfo {
+		if info.short == 0 {
+			continue
+		}
+		if other, ok := c.parent.shortOwner(name, info.short); ok {
+			panic(fmt.Sprintf("console: short name %q of flag %q is already used by %q", info.short, name, other))
+		}
+	}
+	for _, child := range c.children {
+		child.checkShorts()
+	}
+}
+
+// Env binds an environment variable of the client to a declared flag. The
+// console client sends its value when the flag is not given.
+func (c *commonCommandInfo) Env(name, variable string) Command {
+	return c.updateFlag(name, "environment variable", func(info *commonFlagInfo) {
+		info.env = variable
+	})
+}
+
+// FromFile lets the console client read the values of string flags from
+// files, "--payload @payload.json" sends the content of payload.json and
+// "--payload @-" the content of stdin. Other flags are sent as typed, so
+// values starting with @ are not mangled.
+func (c *commonCommandInfo) FromFile(names ...string) Command {
+	for _, name := range names {
+		c.updateFlag(name, "file input", func(info *commonFlagInfo) {
+			if t := info.valueData.Type(); t != "string" && t != "[]string" {
+				panic(fmt.Sprintf("console: flag %q of type %s cannot be read from a file", name, t))
+			}
+			info.fromFile = true
+		})
+	}
+	return c
+}
+
+func (c *commonCommandInfo) child(name string) (*commonCommandInfo, bool) {
+	if child, ok := c.children[name]; ok {
+		return child, true
+	}
+	for _, child := range c.children {
+		for _, alias := range child.aliases {
+			if alias == name {
+				return child, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (c *commonCommandInfo) path() []string {
+	var path []string
+	for n := c; n.parent != nil; n = n.parent {
+		path = append([]string{n.Name}, path...)
+	}
+	return path
+}
+
+type commandSummary struct {
+	name        string
+	aliases     []string
+	description string
+}
+
+// resolvedCommand is a snapshot of a command taken while holding the lock
+// of the registry, so later declarations do not race with the connection
+// executing it.
+type resolvedCommand struct {
+	info        commonCommandInfo
+	path        []string
+	inherited   map[string]commonFlagInfo
+	rules       []flagRule
+	subcommands []commandSummary
+}
+
+func (c resolvedCommand) isGroup() bool {
+	return c.info.ExecuteCallback == nil
+}
+
+// flags returns the flags of the command together with those inherited
+// from its groups, a flag of the command hides an inherited one.
+func (c resolvedCommand) flags() map[string]commonFlagInfo {
+	flags := make(map[string]commonFlagInfo, len(c.inherited)+len(c.info.flagsInfo))
+	for key, info := range c.inherited {
+		flags[key] = info
+	}
+	for key, info := range c.info.flagsInfo {
+		flags[key] = info
+	}
+	return flags
+}
+
+func copyFlags(flags map[string]commonFlagInfo) map[string]commonFlagInfo {
+	c := make(map[string]commonFlagInfo, len(flags))
+	for key, info := range flags {
+		c[key] = info
+	}
+	return c
+}
+
+// lookup resolves a command from its space separated path, e.g.
+// "cache flush" or an alias of it. The empty name resolves to the root of
+// the registry. The path of the result holds the canonical names.
+func (r *Registry) lookup(name string) (resolvedCommand, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var path []string
+	node := r.root
+	inherited := make(map[string]commonFlagInfo)
+	rules := append([]flagRule(nil), node.rules...)
+	for _, part := range strings.Fields(name) {
+		child, ok := node.child(part)
+		if !ok {
+			return resolvedCommand{}, fmt.Errorf("%w %q", ErrUnknownCommand, strings.Join(strings.Fields(name), " "))
+		}
+		for key, info := range node.flagsInfo {
+			inherited[key] = info
+		}
+		node = child
+		path = append(path, node.Name)
+		rules = append(rules, node.rules...)
+	}
+
+	rc := resolvedCommand{
+		info:      *node,
+		path:      path,
+		inherited: inherited,
+		rules:     rules,
+	}
+	rc.info.flagsInfo = copyFlags(node.flagsInfo)
+	rc.info.aliases = append([]string(nil), node.aliases...)
+	rc.info.rules = nil
+	rc.info.children, rc.info.parent = nil, nil
+	for _, child := range node.children {
+		rc.subcommands = append(rc.subcommands, commandSummary{
+			name:        child.Name,
+			aliases:     append([]string(nil), child.aliases...),
+			description: child.Description,
+		})
+	}
+	sort.Slice(rc.subcommands, func(i, j int) bool {
+		return rc.subcommands[i].name < rc.subcommands[j].name
+	})
+
+	return rc, nil
+}
@@ -0,0 +1,283 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r1, r2 := NewRegistry(), NewRegistry()
+	r1.RegisterCommand("asd", "", func(in Input, out Output) error {
+		fmt.Fprint(out, "asd1")
+		return nil
+	})
+	r2.RegisterCommand("asd", "", func(in Input, out Output) error {
+		fmt.Fprint(out, "asd2")
+		return nil
+	})
+
+	l1 := NewCommandListener("")
+	l1.Registry = r1
+	l2 := NewCommandListener("")
+	l2.Registry = r2
+
+	if out, _ := runCommand(t, l1, CommandMessage{Name: "asd"}); out != "asd1" {
+		t.Errorf("runCommand(l1) = %q; want %q", out, "asd1")
+	}
+	if out, _ := runCommand(t, l2, CommandMessage{Name: "asd"}); out != "asd2" {
+		t.Errorf("runCommand(l2) = %q; want %q", out, "asd2")
+	}
+	if _, err := DefaultRegistry.lookup("asd"); err == nil {
+		t.Errorf("DefaultRegistry.lookup(\"asd\") found a command registered in another registry")
+	}
+
+	_, exitErr := runCommand(t, l1, CommandMessage{Name: "asd2"})
+	if exitErr != `unknown command "asd2"` {
+		t.Errorf("runCommand(l1, unknown) error = %q; want %q", exitErr, `unknown command "asd2"`)
+	}
+}
+
+func TestRegistryConcurrentUpdates(t *testing.T) {
+	r, l := newListener()
+
+	onExec := func(in Input, out Output) error {
+		var asd int
+		in.ParseInt(&asd, "asd")
+		fmt.Fprint(out, asd)
+		return nil
+	}
+	r.ReplaceCommand("asd", "", onExec).OptionalInt("asd", "", 1)
+
+	// the command being run is replaced with all its flags at once
+	stop := make(chan struct{})
+	replaced := make(chan struct{})
+	go func() {
+		defer close(replaced)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				r.Replace(NewCommand("asd", "", onExec).OptionalInt("asd", "", 1))
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				name := fmt.Sprintf("asd%d", i)
+				r.ReplaceCommand(name, "", onExec).OptionalInt("asd", "", j).OptionalString("asd2", "", "asd")
+				r.ReplaceCommand(name, "", onExec).OptionalInt("asd", "", j+1)
+				r.UnregisterCommand(name)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				out, exitErr := runCommand(t, l, CommandMessage{Name: "asd"})
+				if exitErr != "" || out != "1" {
+					t.Errorf("runCommand() = %q, %q; want %q, %q", out, exitErr, "1", "")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	<-replaced
+
+	if r.UnregisterCommand("asd0") {
+		t.Errorf("UnregisterCommand(\"asd0\") = true; want false")
+	}
+	if !r.UnregisterCommand("asd") {
+		t.Errorf("UnregisterCommand(\"asd\") = false; want true")
+	}
+}
+
+func TestRegisterCommand(t *testing.T) {
+	r := NewRegistry()
+	onExec := func(in Input, out Output) error { return nil }
+
+	cmd, err := r.RegisterCommand("asd", "", onExec)
+	if err != nil {
+		t.Fatalf("RegisterCommand() error = %v", err)
+	}
+	if cmd != Command(r.root.children["asd"]) {
+		t.Errorf("RegisterCommand() returned a copy of the registered command")
+	}
+
+	if _, err := r.RegisterCommand("asd", "", onExec); !errors.Is(err, ErrDuplicateCommand) {
+		t.Errorf("RegisterCommand() duplicate error = %v; want %v", err, ErrDuplicateCommand)
+	}
+
+	r.ReplaceCommand("asd", "asd2", onExec)
+	if c, _ := r.lookup("asd"); c.info.Description != "asd2" {
+		t.Errorf("ReplaceCommand() description = %q; want %q", c.info.Description, "asd2")
+	}
+
+	group := NewCommand("grp", "", nil).OptionalString("region", "", "eu")
+	sub, _ := group.RegisterCommand("sub", "", onExec)
+	sub.Alias("s")
+	added := r.Replace(group)
+	group.OptionalInt("late", "", 1)
+	c, err := r.lookup("grp s")
+	if err != nil || c.inherited["region"].valueData == nil {
+		t.Errorf("Replace() lookup(\"grp s\") = %+v, %v; want sub inheriting --region", c, err)
+	}
+	if _, ok := c.inherited["late"]; ok {
+		t.Errorf("Replace() added a flag declared after it")
+	}
+	if added == group {
+		t.Errorf("Replace() returned the command built outside the registry")
+	}
+}
+
+func TestCommandGroups(t *testing.T) {
+	r, l := newListener()
+
+	cache, err := r.RegisterGroup("cache", "Cache commands")
+	if err != nil {
+		t.Fatalf("RegisterGroup() error = %v", err)
+	}
+	cache.OptionalString("region", "Region", "eu")
+	cache.RegisterCommand("flush", "Flushes the cache", func(in Input, out Output) error {
+		var region string
+		in.ParseString(&region, "region")
+		fmt.Fprint(out, region)
+		return nil
+	})
+
+	if out, _ := runCommand(t, l, CommandMessage{Name: "cache flush"}); out != "eu" {
+		t.Errorf("runCommand(\"cache flush\") = %q; want %q", out, "eu")
+	}
+	msg := CommandMessage{Name: "cache  flush", Flags: map[string][]string{"region": {"us"}}}
+	if out, _ := runCommand(t, l, msg); out != "us" {
+		t.Errorf("runCommand(\"cache flush --region us\") = %q; want %q", out, "us")
+	}
+	if _, exitErr := runCommand(t, l, CommandMessage{Name: "cache flsh"}); exitErr != `unknown command "cache flsh"` {
+		t.Errorf("runCommand(\"cache flsh\") error = %q; want %q", exitErr, `unknown command "cache flsh"`)
+	}
+
+	for _, name := range []string{"cache", "help cache"} {
+		out, _ := runCommand(t, l, CommandMessage{Name: name})
+		if !strings.Contains(out, "Usage: cache <command>") || !strings.Contains(out, "flush") || !strings.Contains(out, "--region") {
+			t.Errorf("runCommand(%q) = %q; want help of cache", name, out)
+		}
+	}
+	out, _ := runCommand(t, l, CommandMessage{Name: "cache flush", Flags: map[string][]string{"help": nil}})
+	if !strings.Contains(out, "Inherited flags:") {
+		t.Errorf("runCommand(\"cache flush --help\") = %q; want inherited flags", out)
+	}
+}
+
+func TestAliasesAndShortFlags(t *testing.T) {
+	r, l := newListener()
+
+	cmd, _ := r.RegisterCommand("cache-flush", "Flushes the cache", func(in Input, out Output) error {
+		var region string
+		in.ParseString(&region, "region")
+		fmt.Fprint(out, region)
+		return nil
+	})
+	cmd.OptionalString("region", "Region", "eu").Short("region", 'r').Alias("ff")
+
+	msg := CommandMessage{Name: "ff", Flags: map[string][]string{"r": {"us"}}}
+	if out, exitErr := runCommand(t, l, msg); out != "us" {
+		t.Errorf("runCommand(\"ff -r us\") = %q, %q; want %q", out, exitErr, "us")
+	}
+	if _, err := r.RegisterCommand("ff", "", nil); !errors.Is(err, ErrDuplicateCommand) {
+		t.Errorf("RegisterCommand(\"ff\") error = %v; want %v", err, ErrDuplicateCommand)
+	}
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"f"}, []string{"ff"}},
+		{[]string{""}, []string{"cache-flush", "ff"}},
+		{[]string{"ff", "-"}, []string{"--region", "-r"}},
+		{[]string{"ff", "--region", ""}, nil},
+	}
+	for _, test := range tests {
+		if got := r.complete(test.args); !stringsEqual(got, test.expected) {
+			t.Errorf("complete(%q) = %q; want %q", test.args, got, test.expected)
+		}
+	}
+}
+
+func TestShortNameClashes(t *testing.T) {
+	onExec := func(in Input, out Output) error { return nil }
+	tests := []struct {
+		name    string
+		declare func(r *Registry)
+	}{
+		{"subcommand flag after group flag", func(r *Registry) {
+			group, _ := r.RegisterGroup("cache", "")
+			group.OptionalString("region", "", "eu").Short("region", 'r')
+			cmd, _ := group.RegisterCommand("flush", "", onExec)
+			cmd.OptionalBool("recursive", "", false).Short("recursive", 'r')
+		}},
+		{"group flag after subcommand flag", func(r *Registry) {
+			group, _ := r.RegisterGroup("cache", "")
+			cmd, _ := group.RegisterCommand("flush", "", onExec)
+			cmd.OptionalBool("recursive", "", false).Short("recursive", 'r')
+			group.OptionalString("region", "", "eu").Short("region", 'r')
+		}},
+		{"replaced subcommand", func(r *Registry) {
+			group, _ := r.RegisterGroup("cache", "")
+			group.OptionalString("region", "", "eu").Short("region", 'r')
+			group.Replace(NewCommand("flush", "", onExec).OptionalBool("recursive", "", false).Short("recursive", 'r'))
+		}},
+	}
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("declaring -r for --region and --recursive with a %s did not panic", test.name)
+				}
+			}()
+			test.declare(NewRegistry())
+		}()
+	}
+
+	// a subcommand may redeclare a flag of its group with the same short name
+	r, l := newListener()
+	group, _ := r.RegisterGroup("cache", "")
+	group.OptionalString("region", "", "eu").Short("region", 'r').OptionalBool("all", "", false)
+	cmd, _ := group.RegisterCommand("flush", "", func(in Input, out Output) error {
+		var region string
+		in.ParseString(&region, "region")
+		fmt.Fprint(out, region)
+		return nil
+	})
+	cmd.OptionalString("region", "", "us").Short("region", 'r').OptionalBool("recursive", "", false).Short("recursive", 'R')
+
+	msg := CommandMessage{Name: "cache flush", Flags: map[string][]string{"r": {"ap"}, "R": nil}}
+	if out, exitErr := runCommand(t, l, msg); out != "ap" || exitErr != "" {
+		t.Errorf("runCommand(\"cache flush -r ap -R\") = %q, %q; want %q", out, exitErr, "ap")
+	}
+}
+
+func TestFromFile(t *testing.T) {
+	r := NewRegistry()
+	cmd, _ := r.RegisterCommand("import", "", func(in Input, out Output) error { return nil })
+	cmd.RequiredString("payload", "").FromFile("payload").OptionalInt("count", "", 1)
+
+	if d, _ := r.Describe("import"); !d.Flags[1].FromFile || d.Flags[0].FromFile {
+		t.Errorf("Describe(\"import\").Flags = %+v; want only --payload read from files", d.Flags)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("FromFile(\"count\") did not panic for an int flag")
+		}
+	}()
+	cmd.FromFile("count")
+}
@@ -0,0 +1,348 @@
+package console
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	schemaCommand = "schema"
+
+	jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// JSONSchema describes the commands of the registry as a JSON Schema of
+// an invocation, e.g. {"command": "cache flush", "flags": {"region": "eu"}}.
+// The flags of every command are defined under $defs, named after the
+// path of the command with dots, e.g. "cache.flush".
+func (r *Registry) JSONSchema() ([]byte, error) {
+	commands, err := r.schemaCommands()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(commands))
+	defs := make(map[string]any, len(commands))
+	cases := make([]any, len(commands))
+	for i, c := range commands {
+		names[i] = c.name
+		defs[c.key] = c.flags
+		then := map[string]any{
+			"properties": map[string]any{"flags": map[string]any{"$ref": "#/$defs/" + c.key}},
+		}
+		if c.required {
+			then["required"] = []string{"flags"}
+		}
+		cases[i] = map[string]any{
+			"if":   map[string]any{"properties": map[string]any{"command": map[string]any{"const": c.name}}},
+			"then": then,
+		}
+	}
+
+	doc := map[string]any{
+		"$schema":  jsonSchemaDialect,
+		"title":    "Commands",
+		"type":     "object",
+		"required": []string{"command"},
+		"properties": map[string]any{
+			"command": map[string]any{"enum": names},
+			"flags":   map[string]any{"type": "object"},
+		},
+		"additionalProperties": false,
+		"$defs":                defs,
+	}
+	if len(cases) > 0 {
+		doc["allOf"] = cases
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// OpenAPI describes the commands of the registry as an OpenAPI 3.1
+// document for tools reading OpenAPI. Every command is a POST operation
+// under its path, e.g. /cache/flush, taking its flags as a JSON object.
+// The package serves no HTTP, the document only names the commands.
+func (r *Registry) OpenAPI(title, version string) ([]byte, error) {
+	commands, err := r.schemaCommands()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]any, len(commands))
+	schemas := make(map[string]any, len(commands))
+	for _, c := range commands {
+		schemas[c.key] = c.flags
+		op := map[string]any{
+			"operationId": c.key,
+			"requestBody": map[string]any{
+				"required": c.required,
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": map[string]any{"$ref": "#/components/schemas/" + c.key},
+					},
+				},
+			},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "The output of the command.",
+					"content": map[string]any{
+						"text/plain": map[string]any{"schema": map[string]any{"type": "string"}},
+					},
+				},
+				"default": map[string]any{"description": "The command failed."},
+			},
+		}
+		if c.description != "" {
+			op["summary"] = c.description
+		}
+		if c.group != "" {
+			op["tags"] = []string{c.group}
+		}
+		if c.idempotent {
+			op["x-idempotent"] = true
+		}
+		paths["/"+strings.ReplaceAll(c.name, " ", "/")] = map[string]any{"post": op}
+	}
+
+	doc := map[string]any{
+		"openapi":           "3.1.0",
+		"jsonSchemaDialect": jsonSchemaDialect,
+		"info":              map[string]any{"title": title, "version": version},
+		"paths":             paths,
+		"components":        map[string]any{"schemas": schemas},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// commandSchema is a command of the registry with the schema of its flags.
+type commandSchema struct {
+	name, key, group string
+	description      string
+	idempotent       bool
+	required         bool
+	flags            map[string]any
+}
+
+// schemaCommands returns the commands of the registry which can be run,
+// groups only add their flags to their subcommands.
+func (r *Registry) schemaCommands() ([]commandSchema, error) {
+	var commands []commandSchema
+	var walk func(name string) error
+	walk = func(name string) error {
+		rc, err := r.lookup(name)
+		if err != nil {
+			return err
+		}
+		if len(rc.path) > 0 && !rc.isGroup() {
+			flags, required := flagsSchema(rc)
+			commands = append(commands, commandSchema{
+				name:        strings.Join(rc.path, " "),
+				key:         strings.Join(rc.path, "."),
+				group:       strings.Join(rc.path[:len(rc.path)-1], " "),
+				description: rc.info.Description,
+				idempotent:  rc.info.idempotent,
+				required:    required,
+				flags:       flags,
+			})
+		}
+		for _, sub := range rc.subcommands {
+			// a subcommand may be unregistered in the meantime
+			if err := walk(strings.TrimSpace(name + " " + sub.name)); err != nil && !errors.Is(err, ErrUnknownCommand) {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+// flagsSchema returns the schema of the flags object of the command and
+// whether the command has required flags.
+func flagsSchema(rc resolvedCommand) (map[string]any, bool) {
+	flags := rc.flags()
+	properties := make(map[string]any, len(flags))
+	var required []string
+	for _, name := range sortedKeys(flags) {
+		info := flags[name]
+		properties[name] = flagSchema(info)
+		if info.isRequired {
+			required = append(required, name)
+		}
+	}
+
+	s := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if rc.info.Description != "" {
+		s["description"] = rc.info.Description
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	var rules []any
+	for _, rule := range rc.rules {
+		rules = append(rules, rule.schema())
+	}
+	if len(rules) > 0 {
+		s["allOf"] = rules
+	}
+	return s, len(required) > 0
+}
+
+func flagSchema(info commonFlagInfo) map[string]any {
+	typ := info.valueData.Type()
+	s := typeSchema(typ)
+
+	// values are constrained one by one, the count of values as a whole
+	value := s
+	if items, ok := s["items"].(map[string]any); ok {
+		value = items
+		if info.constraints.minCount != nil {
+			s["minItems"] = *info.constraints.minCount
+		}
+		if info.constraints.maxCount != nil {
+			s["maxItems"] = *info.constraints.maxCount
+		}
+	}
+	if len(info.choices) > 0 {
+		value["enum"] = info.choices
+	}
+	// strings like durations can not have a minimum in JSON schema
+	bound := func(v float64) any { return v }
+	minimum, maximum := "minimum", "maximum"
+	if value["type"] == "string" {
+		bound = func(v float64) any { return info.constraints.bound(v) }
+		minimum, maximum = "x-minimum", "x-maximum"
+	}
+	if info.constraints.min != nil {
+		value[minimum] = bound(*info.constraints.min)
+	}
+	if info.constraints.max != nil {
+		value[maximum] = bound(*info.constraints.max)
+	}
+	if info.constraints.minLength != nil {
+		value["minLength"] = *info.constraints.minLength
+	}
+	if info.constraints.maxLength != nil {
+		value["maxLength"] = *info.constraints.maxLength
+	}
+	if info.constraints.pattern != nil {
+		value["pattern"] = info.constraints.pattern.String()
+	}
+
+	if info.description != "" {
+		s["description"] = info.description
+	}
+	if info.secret {
+		s["writeOnly"] = true
+	} else if v := info.valueData.Get(); v != nil {
+		s["default"] = schemaValue(typ, v)
+	}
+	return s
+}
+
+// typeSchema maps the type of a flag to JSON types. Types without a JSON
+// counterpart are strings in the format parsed by the server, with their
+// Go type in x-go-type.
+func typeSchema(typ string) map[string]any {
+	if elem, ok := strings.CutPrefix(typ, "[]"); ok {
+		return map[string]any{"type": "array", "items": typeSchema(elem)}
+	}
+	switch typ {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint64":
+		return map[string]any{"type": "integer"}
+	case "float32", "float64":
+		return map[string]any{"type": "number"}
+	case "bool":
+		return map[string]any{"type": "boolean"}
+	case "string":
+		return map[string]any{"type": "string"}
+	case "map[string]string":
+		return map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}}
+	case "time.Time":
+		return map[string]any{"type": "string", "format": "date-time", "x-go-type": typ}
+	case "*url.URL":
+		return map[string]any{"type": "string", "format": "uri", "x-go-type": typ}
+	case "*regexp.Regexp":
+		return map[string]any{"type": "string", "format": "regex", "x-go-type": typ}
+	}
+	return map[string]any{"type": "string", "x-go-type": typ}
+}
+
+// schemaValue converts the default of a flag to the JSON type of its
+// schema.
+func schemaValue(typ string, v any) any {
+	if elem, ok := strings.CutPrefix(typ, "[]"); ok {
+		rv := reflect.ValueOf(v)
+		values := make([]any, rv.Len())
+		for i := range values {
+			values[i] = schemaValue(elem, rv.Index(i).Interface())
+		}
+		return values
+	}
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	if s := typeSchema(typ); s["x-go-type"] != nil {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+// schema expresses the rule in JSON Schema, to be combined with allOf.
+func (r flagRule) schema() map[string]any {
+	switch r.kind {
+	case ruleMutuallyExclusive:
+		var pairs []any
+		for i, a := range r.names {
+			for _, b := range r.names[i+1:] {
+				pairs = append(pairs, map[string]any{"required": []string{a, b}})
+			}
+		}
+		return map[string]any{"not": map[string]any{"anyOf": pairs}}
+	case ruleAtLeastOneOf:
+		var anyOf []any
+		for _, name := range r.names {
+			anyOf = append(anyOf, map[string]any{"required": []string{name}})
+		}
+		return map[string]any{"anyOf": anyOf}
+	case ruleRequires:
+		return map[string]any{"dependentRequired": map[string]any{r.names[0]: r.names[1:]}}
+	}
+	return map[string]any{}
+}
+
+// writeSchema runs the built-in "schema [--format openapi]" command.
+func writeSchema(out Output, r *Registry, flags map[string][]string) error {
+	format := lastValue(flags, "format", "json-schema")
+	var b []byte
+	var err error
+	switch format {
+	case "json-schema":
+		b, err = r.JSONSchema()
+	case "openapi":
+		b, err = r.OpenAPI(lastValue(flags, "title", "Commands"), lastValue(flags, "version", "1.0.0"))
+	default:
+		return fmt.Errorf("flag --format must be one of json-schema, openapi, got %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", b)
+	return err
+}
+
+func lastValue(flags map[string][]string, name, fallback string) string {
+	if values := flags[name]; len(values) > 0 {
+		return values[len(values)-1]
+	}
+	return fallback
+}
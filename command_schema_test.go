@@ -0,0 +1,96 @@
+package console
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchema(t *testing.T) {
+	r, l := newListener()
+
+	cache, _ := r.RegisterGroup("cache", "Cache commands")
+	cache.OptionalEnum("region", "Region", "eu", "eu", "us")
+	flush, _ := cache.RegisterCommand("flush", "Flushes the cache", func(in Input, out Output) error { return nil })
+	flush.RequiredInt("level", "").Min("level", 1).Max("level", 3).
+		OptionalSliceString("key", "", nil).Pattern("key", "^[a-z]+$").MaxCount("key", 2).
+		OptionalDuration("wait", "", time.Second).Max("wait", float64(time.Minute)).
+		RequiredString("token", "").Secret("token").
+		Requires("wait", "key")
+	flush.Idempotent()
+
+	b, err := r.JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema() error = %v", err)
+	}
+	var schema struct {
+		Properties struct {
+			Command struct {
+				Enum []string `json:"enum"`
+			} `json:"command"`
+		} `json:"properties"`
+		Defs map[string]map[string]any `json:"$defs"`
+	}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatalf("JSONSchema() = %s, error = %v", b, err)
+	}
+	if !reflect.DeepEqual(schema.Properties.Command.Enum, []string{"cache flush"}) {
+		t.Errorf("command enum = %v; want [cache flush]", schema.Properties.Command.Enum)
+	}
+	flags := schema.Defs["cache.flush"]
+	expected := map[string]any{
+		"type":                 "object",
+		"description":          "Flushes the cache",
+		"additionalProperties": false,
+		"required":             []any{"level", "token"},
+		"allOf":                []any{map[string]any{"dependentRequired": map[string]any{"wait": []any{"key"}}}},
+		"properties": map[string]any{
+			"region": map[string]any{"type": "string", "enum": []any{"eu", "us"}, "description": "Region", "default": "eu"},
+			"level":  map[string]any{"type": "integer", "minimum": 1.0, "maximum": 3.0},
+			"key":    map[string]any{"type": "array", "items": map[string]any{"type": "string", "pattern": "^[a-z]+$"}, "maxItems": 2.0, "default": []any{}},
+			"wait":   map[string]any{"type": "string", "x-go-type": "time.Duration", "default": "1s", "x-maximum": "1m0s"},
+			"token":  map[string]any{"type": "string", "writeOnly": true},
+		},
+	}
+	if !reflect.DeepEqual(flags, expected) {
+		t.Errorf("$defs[cache.flush] = %v; want %v", flags, expected)
+	}
+
+	b, err = r.OpenAPI("Admin", "1.2.0")
+	if err != nil {
+		t.Fatalf("OpenAPI() error = %v", err)
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]struct {
+			Post struct {
+				OperationID string   `json:"operationId"`
+				Tags        []string `json:"tags"`
+				Idempotent  bool     `json:"x-idempotent"`
+			} `json:"post"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("OpenAPI() = %s, error = %v", b, err)
+	}
+	op := doc.Paths["/cache/flush"].Post
+	if doc.OpenAPI != "3.1.0" || op.OperationID != "cache.flush" || !reflect.DeepEqual(op.Tags, []string{"cache"}) || !op.Idempotent {
+		t.Errorf("OpenAPI() = %s; want operation cache.flush", b)
+	}
+	if !reflect.DeepEqual(doc.Components.Schemas["cache.flush"], expected) {
+		t.Errorf("components.schemas[cache.flush] = %v; want %v", doc.Components.Schemas["cache.flush"], expected)
+	}
+
+	out, exitErr := runCommand(t, l, CommandMessage{Name: "schema", Flags: map[string][]string{"format": {"openapi"}}})
+	if exitErr != "" || !strings.Contains(out, `"openapi": "3.1.0"`) {
+		t.Errorf("schema --format openapi = %q, %q; want the OpenAPI document", out, exitErr)
+	}
+	if _, exitErr := runCommand(t, l, CommandMessage{Name: "schema", Flags: map[string][]string{"format": {"yaml"}}}); exitErr == "" {
+		t.Errorf("schema --format yaml error = nil; want unknown format")
+	}
+}
@@ -0,0 +1,60 @@
+package console
+
+import "fmt"
+
+const redacted = "****"
+
+// Secret marks flags holding passwords or keys. Their values are never
+// printed by the listener, in help or in validation errors, and the
+// operator is asked for them without echo when they are required but not
+// sent, or sent without a value.
+func (c *commonCommandInfo) Secret(names ...string) Command {
+	for _, name := range names {
+		c.updateFlag(name, "secret", func(info *commonFlagInfo) {
+			info.secret = true
+		})
+	}
+	return c
+}
+
+// redactFlags returns flags with the values of secret flags replaced, so
+// that they can be printed.
+func redactFlags(flags map[string][]string, flagsInfo map[string]commonFlagInfo) map[string][]string {
+	out := make(map[string][]string, len(flags))
+	for key, values := range flags {
+		name := key
+		if short, ok := shortFlagName(key, flagsInfo); ok {
+			name = short
+		}
+		if flagsInfo[name].secret && values != nil {
+			values = make([]string, len(values))
+			for i := range values {
+				values[i] = redacted
+			}
+		}
+		out[key] = values
+	}
+	return out
+}
+
+// promptSecrets asks the operator for the secret flags which are missing.
+func promptSecrets(flags map[string][]string, flagsInfo map[string]commonFlagInfo, p Prompter) error {
+	for _, name := range sortedKeys(flagsInfo) {
+		info := flagsInfo[name]
+		values, sent := flags[name]
+		if !info.secret || len(values) > 0 || (!sent && !info.isRequired) {
+			continue
+		}
+
+		question := info.description
+		if question == "" {
+			question = "--" + name
+		}
+		value, err := p.PromptSecret(question)
+		if err != nil {
+			return fmt.Errorf("flag --%s: %w", name, err)
+		}
+		flags[name] = []string{value}
+	}
+	return nil
+}
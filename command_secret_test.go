@@ -0,0 +1,65 @@
+package console
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestSecretFlags(t *testing.T) {
+	r, l := newListener()
+
+	cmd, _ := r.RegisterCommand("login", "", func(in Input, out Output) error {
+		var password string
+		in.ParseString(&password, "password")
+		fmt.Fprint(out, password)
+		return nil
+	})
+	cmd.RequiredString("password", "Password").Short("password", 'p').MinLength("password", 4).Secret("password")
+
+	if out, _ := runCommand(t, l, CommandMessage{Name: "login", Flags: map[string][]string{"p": {"hunter2"}}}); out != "hunter2" {
+		t.Errorf("runCommand(\"login -p hunter2\") = %q; want %q", out, "hunter2")
+	}
+	_, exitErr := runCommand(t, l, CommandMessage{Name: "login", Flags: map[string][]string{"password": {"abc"}}})
+	if expected := "flag --password must be at least 4 characters long"; exitErr != expected {
+		t.Errorf("runCommand(\"login --password abc\") error = %q; want %q", exitErr, expected)
+	}
+	if out, _ := runCommand(t, l, CommandMessage{Name: "login", Flags: map[string][]string{"help": nil}}); !strings.Contains(out, "(required) (secret)") {
+		t.Errorf("runCommand(\"login --help\") = %q; want --password marked secret", out)
+	}
+
+	redactedFlags := l.redact(CommandMessage{Name: "login", Flags: map[string][]string{"p": {"hunter2"}}}).Flags
+	if got := redactedFlags["p"]; !stringsEqual(got, []string{"****"}) {
+		t.Errorf("redact() = %q; want %q", got, []string{"****"})
+	}
+
+	// a missing secret is asked for
+	server, client := net.Pipe()
+	defer client.Close()
+	go l.handleConnection(server)
+	if err := WriteFrame(client, FrameCommand, CommandMessage{Name: "login"}.ToBytes()); err != nil {
+		t.Fatalf("WriteFrame() error = %v", err)
+	}
+	stdout := ""
+	for done := false; !done; {
+		ft, payload, err := ReadFrame(client)
+		if err != nil {
+			t.Fatalf("ReadFrame() error = %v", err)
+		}
+		switch ft {
+		case FramePrompt:
+			if p, err := PromptRequestFromBytes(payload); err != nil || p.Kind != PromptSecret || p.Question != "Password" {
+				t.Errorf("prompt = %+v, %v; want secret prompt for Password", p, err)
+			}
+			WriteFrame(client, FramePromptReply, PromptReply{Value: "s3cret"}.ToBytes())
+		case FrameStdout:
+			stdout += string(payload)
+		case FrameExit:
+			done = true
+		}
+	}
+	if stdout != "s3cret" {
+		t.Errorf("prompted password = %q; want %q", stdout, "s3cret")
+	}
+}
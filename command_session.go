@@ -0,0 +1,274 @@
+package console
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	errSessionClosed = errors.New("connection closed")
+	errIdleTimeout   = errors.New("connection idle for too long")
+)
+
+type dataChunk struct {
+	data []byte
+	eof  bool
+}
+
+type frame struct {
+	t       FrameType
+	payload []byte
+}
+
+// connection serves the commands a client sends one after another. Frames
+// are read by a single goroutine and consumed by readCommand between
+// commands and by the readLoop of the session while a command runs.
+type connection struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+	logger  *slog.Logger
+
+	frames        chan frame
+	quit          chan struct{}
+	authenticated bool
+}
+
+func newConnection(conn net.Conn, logger *slog.Logger) *connection {
+	c := &connection{
+		conn:   conn,
+		logger: logger,
+		frames: make(chan frame),
+		quit:   make(chan struct{}),
+	}
+	go c.readFrames()
+	return c
+}
+
+func (c *connection) readFrames() {
+	defer close(c.frames)
+
+	for {
+		t, payload, err := ReadFrame(c.conn)
+		if err != nil {
+			return
+		}
+		c.logger.Debug("frame received", "type", t, "size", len(payload))
+
+		select {
+		case c.frames <- frame{t: t, payload: payload}:
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+func (c *connection) close() error {
+	close(c.quit)
+	return c.conn.Close()
+}
+
+func (c *connection) writeFrame(t FrameType, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.logger.Debug("frame sent", "type", t, "size", len(payload))
+	return WriteFrame(c.conn, t, payload)
+}
+
+// readCommand reads the next command to run. The client may first send its
+// token, ask for descriptions, which describe returns, and ping. With
+// authenticate set nothing is described or run before the client sent a
+// token accepted by it. Without any command or description for idle the
+// connection is given up, pings do not keep it, zero waits forever.
+func (c *connection) readCommand(describe func(name string) []byte, authenticate func(token string) error, idle time.Duration) (CommandMessage, error) {
+	if authenticate == nil {
+		c.authenticated = true
+	}
+	var timeout <-chan time.Time
+	resetIdle := func() {
+		if idle > 0 {
+			timeout = time.After(idle)
+		}
+	}
+	resetIdle()
+	for {
+		var f frame
+		var ok bool
+		select {
+		case f, ok = <-c.frames:
+			if !ok {
+				return CommandMessage{}, io.EOF
+			}
+		case <-timeout:
+			return CommandMessage{}, errIdleTimeout
+		}
+
+		switch {
+		case f.t == FrameAuth && authenticate != nil:
+			if err := authenticate(string(f.payload)); err != nil {
+				return CommandMessage{}, fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+			}
+			c.authenticated = true
+		case f.t == FrameAuth:
+		case f.t == FramePing:
+			if err := c.writeFrame(FramePong, f.payload); err != nil {
+				return CommandMessage{}, err
+			}
+		case f.t == FrameData || f.t == FrameDataEnd || f.t == FramePromptReply:
+			// sent for the previous command before it exited
+		case !c.authenticated:
+			return CommandMessage{}, ErrUnauthenticated
+		case f.t == FrameCommand:
+			msg, err := MessageFromBytes(f.payload)
+			if err == nil {
+				return msg, nil
+			}
+			// the frames are intact, only this command is rejected
+			c.logger.Warn("malformed command", "error", err)
+			if err := c.writeFrame(FrameExit, []byte("malformed command: "+err.Error())); err != nil {
+				return CommandMessage{}, err
+			}
+			resetIdle()
+		case f.t == FrameDescribe:
+			if err := c.writeFrame(FrameDescription, describe(string(f.payload))); err != nil {
+				return CommandMessage{}, err
+			}
+			resetIdle()
+		default:
+			return CommandMessage{}, errors.New("expected command frame")
+		}
+	}
+}
+
+// session is a single command running on a connection.
+type session struct {
+	c      *connection
+	logger *slog.Logger
+
+	data    chan dataChunk
+	replies chan PromptReply
+	done    chan struct{}
+	closed  chan struct{}
+}
+
+func newSession(c *connection, logger *slog.Logger) *session {
+	return &session{
+		c:       c,
+		logger:  logger,
+		data:    make(chan dataChunk, 1),
+		replies: make(chan PromptReply, 1),
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (s *session) writeFrame(t FrameType, payload []byte) error {
+	return s.c.writeFrame(t, payload)
+}
+
+// readLoop dispatches frames sent by the client while the command runs.
+// Data is only sent in reply to FrameDataRequest, so a slow reader on the
+// server side holds the client back instead of buffering in memory. It
+// returns when the command finished, so that the next command can be read.
+func (s *session) readLoop() {
+	defer close(s.closed)
+
+	for {
+		var f frame
+		var ok bool
+		select {
+		case f, ok = <-s.c.frames:
+			if !ok {
+				return
+			}
+		case <-s.done:
+			return
+		}
+
+		switch f.t {
+		case FrameData:
+			s.deliverData(dataChunk{data: f.payload})
+		case FrameDataEnd:
+			s.deliverData(dataChunk{eof: true})
+		case FramePromptReply:
+			reply, err := PromptReplyFromBytes(f.payload)
+			if err != nil {
+				// the prompt fails instead of waiting for a reply forever
+				reply = PromptReply{Err: "malformed prompt reply: " + err.Error()}
+			}
+			select {
+			case s.replies <- reply:
+			case <-s.done:
+			}
+		case FramePing:
+			s.writeFrame(FramePong, f.payload)
+		}
+	}
+}
+
+func (s *session) deliverData(chunk dataChunk) {
+	select {
+	case s.data <- chunk:
+	case <-s.done:
+	}
+}
+
+// finish reports the exit of the command. The readLoop is stopped first,
+// the client sends its next command once it sees the exit and that frame
+// must be left for readCommand.
+func (s *session) finish(err error) error {
+	close(s.done)
+	<-s.closed
+
+	var msg []byte
+	if err != nil {
+		msg = []byte(err.Error())
+	}
+	return s.writeFrame(FrameExit, msg)
+}
+
+type inputStream struct {
+	mu  sync.Mutex
+	s   *session
+	buf []byte
+	eof bool
+}
+
+func (r *inputStream) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+
+		size := len(p)
+		if size > maxDataChunk {
+			size = maxDataChunk
+		}
+		if err := r.s.writeFrame(FrameDataRequest, binary.BigEndian.AppendUint32(nil, uint32(size))); err != nil {
+			return 0, err
+		}
+
+		select {
+		case chunk := <-r.s.data:
+			r.buf, r.eof = chunk.data, chunk.eof
+		case <-r.s.closed:
+			return 0, errSessionClosed
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,86 @@
+package console
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMalformedFrames(t *testing.T) {
+	r, l := newListener()
+	r.RegisterCommand("ask", "", func(in Input, out Output) error {
+		_, err := in.Prompt("Name", "")
+		return err
+	})
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go l.handleConnection(server)
+
+	exit := func() string {
+		t.Helper()
+		for {
+			ft, payload, err := ReadFrame(client)
+			if err != nil {
+				t.Fatalf("ReadFrame() error = %v", err)
+			}
+			switch ft {
+			case FramePrompt:
+				WriteFrame(client, FramePromptReply, []byte{1})
+			case FrameExit:
+				return string(payload)
+			}
+		}
+	}
+
+	// bad payloads are rejected and the connection keeps serving commands
+	WriteFrame(client, FrameCommand, []byte{0, 0, 0, 9, 'a'})
+	if exitErr := exit(); !strings.HasPrefix(exitErr, "malformed command") {
+		t.Errorf("malformed command error = %q; want malformed command", exitErr)
+	}
+	WriteFrame(client, FrameCommand, CommandMessage{Name: "ask"}.ToBytes())
+	if exitErr := exit(); !strings.HasPrefix(exitErr, "malformed prompt reply") {
+		t.Errorf("malformed prompt reply error = %q; want malformed prompt reply", exitErr)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	_, l := newListener()
+	l.IdleTimeout = 10 * time.Millisecond
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go l.handleConnection(server)
+
+	// the client learns that nothing it sends from now on is read
+	if ft, _, err := ReadFrame(client); err != nil || ft != FrameClose {
+		t.Errorf("ReadFrame() on an idle connection = %v, %v; want %v", ft, err, FrameClose)
+	}
+	if _, _, err := ReadFrame(client); err == nil {
+		t.Errorf("ReadFrame() after %v error = nil; want closed connection", FrameClose)
+	}
+
+	// pings do not keep the connection open
+	l.IdleTimeout = 50 * time.Millisecond
+	server, client = net.Pipe()
+	defer client.Close()
+	go l.handleConnection(server)
+	deadline := time.Now().Add(time.Second)
+	for pings := 0; ; pings++ {
+		if time.Now().After(deadline) {
+			t.Fatalf("connection still open after %d pings", pings)
+		}
+		if err := WriteFrame(client, FramePing, nil); err != nil {
+			t.Fatalf("WriteFrame() error = %v", err)
+		}
+		ft, _, err := ReadFrame(client)
+		if err != nil {
+			t.Fatalf("ReadFrame() error = %v", err)
+		}
+		if ft == FrameClose {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,78 @@
+package console
+
+import (
+	"encoding/binary"
+	"encoding/json"
+)
+
+// StructuredOutput lets commands emit values which the client renders in
+// the format chosen by the operator (json, yaml, table, csv or text)
+// instead of plain text written to the Output.
+type StructuredOutput interface {
+	Record(fields ...Field) error
+	Item(value any) error
+	KeyValue(key string, value any) error
+}
+
+type Field struct {
+	Key   string
+	Value any
+}
+
+type EntryKind byte
+
+const (
+	EntryRecord EntryKind = iota + 1
+	EntryItem
+	EntryKeyValue
+)
+
+// Entry is a single structured value on the wire. Values are JSON encoded,
+// so after EntryFromBytes every Field.Value holds a json.RawMessage.
+type Entry struct {
+	Kind   EntryKind
+	Fields []Field
+}
+
+func EntryFromBytes(data []byte) (Entry, error) {
+	if len(data) == 0 {
+		return Entry{}, errTruncated
+	}
+	e := Entry{Kind: EntryKind(data[0])}
+	n, data, err := readUint32(data[1:])
+	if err != nil {
+		return Entry{}, err
+	}
+	// every field takes at least the lengths of its key and value
+	if uint64(n)*8 > uint64(len(data)) {
+		return Entry{}, errTruncated
+	}
+	e.Fields = make([]Field, n)
+	for i := range e.Fields {
+		var value string
+		if e.Fields[i].Key, data, err = readString(data); err != nil {
+			return Entry{}, err
+		}
+		if value, data, err = readString(data); err != nil {
+			return Entry{}, err
+		}
+		e.Fields[i].Value = json.RawMessage(value)
+	}
+
+	return e, nil
+}
+
+func (e Entry) ToBytes() ([]byte, error) {
+	b := []byte{byte(e.Kind)}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(e.Fields)))
+	for _, f := range e.Fields {
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		b = writeString(b, f.Key)
+		b = writeString(b, string(value))
+	}
+
+	return b, nil
+}
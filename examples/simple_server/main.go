@@ -1,15 +1,57 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"os"
+	"time"
 
 	"github.com/Kankeran/console"
 )
 
 func main() {
-	var cmdInfo = console.RegisterCommand("something", "Test command", OnExec)
-	cmdInfo.OptionalInt("asd", "Getting int value", 123)
-	fmt.Println(console.ListenCommands())
+	mustRegister("something", "Test command", OnExec).
+		OptionalInt("asd", "Getting int value", 123)
+	mustRegister("count-lines", "Counts lines sent on stdin", OnCountLines)
+	mustRegister("drop-tables", "Drops all tables", OnDropTables)
+	mustRegister("tables", "Lists tables", OnTables).
+		Idempotent()
+	mustRegister("migrate", "Runs database migrations", OnMigrate)
+	mustRegister("import", "Imports a JSON document", OnImport).
+		RequiredString("payload", "JSON document to import").
+		FromFile("payload")
+
+	cache, err := console.RegisterGroup("cache", "Manages the cache")
+	if err != nil {
+		panic(err)
+	}
+	cache.OptionalString("region", "Region of the cache", "eu").
+		Short("region", 'r').
+		Env("region", "CACHE_REGION")
+	flush, err := cache.RegisterCommand("flush", "Flushes the cache", OnCacheFlush)
+	if err != nil {
+		panic(err)
+	}
+	flush.Alias("ff").
+		OptionalBool("dry-run", "Only report what would be flushed", false).
+		OptionalEnum("mode", "How entries are removed", "soft", "soft", "hard")
+
+	l := console.NewCommandListener(console.GetAdress())
+	l.Logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if token := os.Getenv("CONSOLE_TOKEN"); token != "" {
+		l.Authenticate = console.TokenAuth(token)
+	}
+	fmt.Println(l.ListenCommands())
+}
+
+func mustRegister(name, description string, callback func(console.Input, console.Output) error) console.Command {
+	cmd, err := console.RegisterCommand(name, description, callback)
+	if err != nil {
+		panic(err)
+	}
+	return cmd
 }
 
 func OnExec(in console.Input, out console.Output) error {
@@ -18,3 +60,97 @@ func OnExec(in console.Input, out console.Output) error {
 	fmt.Fprint(out, "Hello")
 	return nil
 }
+
+func OnCountLines(in console.Input, out console.Output) error {
+	lines := 0
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		lines++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, lines)
+	return nil
+}
+
+func OnDropTables(in console.Input, out console.Output) error {
+	ok, err := in.Confirm("Really drop 12 tables?")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintln(out.Stderr(), "Aborted")
+		return nil
+	}
+
+	for i := 1; i <= 12; i++ {
+		out.Logger().Debugf("dropping table %d", i)
+	}
+	fmt.Fprintln(out.Stdout(), "Dropped 12 tables")
+	return nil
+}
+
+func OnTables(in console.Input, out console.Output) error {
+	tables := []struct {
+		name string
+		rows int
+		tags []string
+	}{
+		{"users", 1200, []string{"core"}},
+		{"orders", 53000, []string{"billing", "core"}},
+	}
+
+	for _, t := range tables {
+		err := out.Record(
+			console.Field{Key: "name", Value: t.name},
+			console.Field{Key: "rows", Value: t.rows},
+			console.Field{Key: "tags", Value: t.tags},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func OnMigrate(in console.Input, out console.Output) error {
+	p := out.Progress("migrate", 20)
+	for i := 1; i <= 20; i++ {
+		p.Status("migration %03d", i)
+		time.Sleep(50 * time.Millisecond)
+		p.Add(1)
+	}
+	p.Done()
+
+	fmt.Fprintln(out, "Migrated")
+	return nil
+}
+
+func OnCacheFlush(in console.Input, out console.Output) error {
+	var region, mode string
+	var dryRun bool
+	in.ParseString(&region, "region")
+	in.ParseString(&mode, "mode")
+	in.ParseBool(&dryRun, "dry-run")
+
+	if dryRun {
+		fmt.Fprintf(out, "Would %s flush cache in %s\n", mode, region)
+		return nil
+	}
+	fmt.Fprintf(out, "Flushed cache in %s (%s)\n", region, mode)
+	return nil
+}
+
+func OnImport(in console.Input, out console.Output) error {
+	var payload string
+	in.ParseString(&payload, "payload")
+
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(payload), &doc); err != nil {
+		return err
+	}
+	out.KeyValue("keys", len(doc))
+	return nil
+}
@@ -1,348 +1,272 @@
 package console
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"strconv"
+	"log/slog"
+	"maps"
+	"net"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
 	"time"
 )
 
 type Input interface {
+	io.Reader
+	Prompter
+
 	ParseInt(variable *int, key string)
+	ParseInt8(variable *int8, key string)
+	ParseInt16(variable *int16, key string)
+	ParseInt32(variable *int32, key string)
 	ParseInt64(variable *int64, key string)
 	ParseUint(variable *uint, key string)
 	ParseUint64(variable *uint64, key string)
 	ParseBool(variable *bool, key string)
 	ParseString(variable *string, key string)
+	ParseFloat32(variable *float32, key string)
 	ParseFloat64(variable *float64, key string)
 	ParseDuration(variable *time.Duration, key string)
+	ParseTime(variable *time.Time, key string)
+	ParseIP(variable *net.IP, key string)
+	ParsePrefix(variable *netip.Prefix, key string)
+	ParseURL(variable **url.URL, key string)
+	ParseByteSize(variable *ByteSize, key string)
+	ParseRegexp(variable **regexp.Regexp, key string)
+	ParseStringMap(variable *map[string]string, key string)
+	ParseVar(variable FlagValue, key string)
 
 	ParseIntSlice(variable *[]int, key string)
 	ParseInt64Slice(variable *[]int64, key string)
 	ParseUintSlice(variable *[]uint, key string)
 	ParseUint64Slice(variable *[]uint64, key string)
+	ParseBoolSlice(variable *[]bool, key string)
 	ParseStringSlice(variable *[]string, key string)
 	ParseFloat64Slice(variable *[]float64, key string)
 	ParseDurationSlice(variable *[]time.Duration, key string)
 }
 
-type Output interface {
-	io.Writer
+// ErrUndeclaredFlag is the error of a command parsing a flag it does not
+// declare. The command stops and the client receives the error.
+var ErrUndeclaredFlag = errors.New("undeclared flag")
+
+type commandInput struct {
+	*FlagParser
+	io.Reader
+	Prompter
 }
 
 type FlagParser struct {
 	flags     map[string][]string
 	flagsInfo map[string]commonFlagInfo
+	logger    *slog.Logger
 }
 
 func (f *FlagParser) getFlagValueData(key string) Value {
+	f.mustBeDeclared(key)
 	return f.flagsInfo[key].valueData
 }
 
+// mustBeDeclared stops the command parsing a flag it does not declare,
+// which may also be a command replaced while it was declaring its flags.
+func (f *FlagParser) mustBeDeclared(key string) {
+	if _, ok := f.flagsInfo[key]; !ok {
+		panic(fmt.Errorf("%w --%s", ErrUndeclaredFlag, key))
+	}
+}
+
 func (f *FlagParser) writeWarning(key, curType, expectedType string) {
-	fmt.Printf("[WARNING] default value for '%s' is of type '%s', should be '%s'\n", key, curType, expectedType)
+	loggerOrDiscard(f.logger).Warn("default value of flag has a wrong type", "flag", key, "type", curType, "expected", expectedType)
 }
 
-func (f *FlagParser) ParseInt(variable *int, key string) {
-	vals := f.flags[key]
+// defaultValue returns the default of an optional flag and whether the
+// variable should be set to it, because the flag was not sent. Defaults
+// holding slices, maps or pointers are shared by every run of the command,
+// callers set variables to copies of them.
+func defaultValue[T any](f *FlagParser, key, expectedType string) (T, bool) {
+	var typedVal T
 	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().(int)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "int")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
+	if defValData.Get() == nil {
+		return typedVal, false
 	}
-	i64, err := strconv.Atoi(vals[0])
-	if err != nil {
-		panic(err)
+
+	typedVal, ok := defValData.Get().(T)
+	if !ok {
+		f.writeWarning(key, defValData.Type(), expectedType)
 	}
-	*variable = int(i64)
+	return typedVal, len(f.flags[key]) == 0
 }
 
-func (f *FlagParser) ParseInt64(variable *int64, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().(int64)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "int64")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
+func parseValue[T any](f *FlagParser, variable *T, key, expectedType string, parse func(string) (T, error)) {
+	if typedVal, ok := defaultValue[T](f, key, expectedType); ok {
+		*variable = typedVal
+		return
 	}
-	i64, err := strconv.ParseInt(vals[0], 10, 64)
+	v, err := parse(f.flags[key][0])
 	if err != nil {
 		panic(err)
 	}
-	*variable = i64
+	*variable = v
 }
 
-func (f *FlagParser) ParseUint(variable *uint, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().(uint)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "uint")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
+func parseSlice[T any](f *FlagParser, variable *[]T, key, expectedType string, parse func(string) (T, error)) {
+	if typedVal, ok := defaultValue[[]T](f, key, expectedType); ok {
+		*variable = slices.Clone(typedVal)
+		return
 	}
-	u64, err := strconv.ParseUint(vals[0], 10, 64)
-	if err != nil {
-		panic(err)
+	for _, s := range f.flags[key] {
+		v, err := parse(s)
+		if err != nil {
+			panic(err)
+		}
+		*variable = append(*variable, v)
 	}
-	*variable = uint(u64)
+}
+
+func (f *FlagParser) ParseInt(variable *int, key string) {
+	parseValue(f, variable, key, "int", parseInt)
+}
+
+func (f *FlagParser) ParseInt8(variable *int8, key string) {
+	parseValue(f, variable, key, "int8", parseInt8)
+}
+
+func (f *FlagParser) ParseInt16(variable *int16, key string) {
+	parseValue(f, variable, key, "int16", parseInt16)
+}
+
+func (f *FlagParser) ParseInt32(variable *int32, key string) {
+	parseValue(f, variable, key, "int32", parseInt32)
+}
+
+func (f *FlagParser) ParseInt64(variable *int64, key string) {
+	parseValue(f, variable, key, "int64", parseInt64)
+}
+
+func (f *FlagParser) ParseUint(variable *uint, key string) {
+	parseValue(f, variable, key, "uint", parseUint)
 }
 
 func (f *FlagParser) ParseUint64(variable *uint64, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().(uint64)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "uint64")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
-	}
-	u64, err := strconv.ParseUint(vals[0], 10, 64)
-	if err != nil {
-		panic(err)
-	}
-	*variable = u64
+	parseValue(f, variable, key, "uint64", parseUint64)
 }
 
 func (f *FlagParser) ParseBool(variable *bool, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().(bool)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "bool")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
-	}
-	b, err := strconv.ParseBool(vals[0])
-	if err != nil {
-		panic(err)
+	f.mustBeDeclared(key)
+	if vals, ok := f.flags[key]; ok && len(vals) == 0 {
+		// a bare --flag
+		*variable = true
+		return
 	}
-	*variable = b
+	parseValue(f, variable, key, "bool", parseBool)
 }
 
 func (f *FlagParser) ParseString(variable *string, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().(string)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "string")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
-	}
-	*variable = vals[0]
+	parseValue(f, variable, key, "string", parseString)
+}
+
+func (f *FlagParser) ParseFloat32(variable *float32, key string) {
+	parseValue(f, variable, key, "float32", parseFloat32)
 }
 
 func (f *FlagParser) ParseFloat64(variable *float64, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().(float64)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "float64")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
-	}
-	f64, err := strconv.ParseFloat(vals[0], 64)
-	if err != nil {
-		panic(err)
-	}
-	*variable = f64
+	parseValue(f, variable, key, "float64", parseFloat64)
 }
 
 func (f *FlagParser) ParseDuration(variable *time.Duration, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().(time.Duration)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "time.Duration")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
+	parseValue(f, variable, key, "time.Duration", time.ParseDuration)
+}
+
+func (f *FlagParser) ParseTime(variable *time.Time, key string) {
+	parseValue(f, variable, key, "time.Time", parseTime)
+}
+
+func (f *FlagParser) ParseIP(variable *net.IP, key string) {
+	if typedVal, ok := defaultValue[net.IP](f, key, "net.IP"); ok {
+		*variable = slices.Clone(typedVal)
+		return
 	}
-	d, err := time.ParseDuration(vals[0])
-	if err != nil {
-		panic(err)
+	parseValue(f, variable, key, "net.IP", parseIP)
+}
+
+func (f *FlagParser) ParsePrefix(variable *netip.Prefix, key string) {
+	parseValue(f, variable, key, "netip.Prefix", netip.ParsePrefix)
+}
+
+func (f *FlagParser) ParseURL(variable **url.URL, key string) {
+	if typedVal, ok := defaultValue[*url.URL](f, key, "*url.URL"); ok {
+		if typedVal != nil {
+			u := *typedVal
+			typedVal = &u
+		}
+		*variable = typedVal
+		return
 	}
-	*variable = d
+	parseValue(f, variable, key, "*url.URL", parseURL)
 }
 
-func (f *FlagParser) ParseIntSlice(variable *[]int, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().([]int)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "[]int")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
+func (f *FlagParser) ParseByteSize(variable *ByteSize, key string) {
+	parseValue(f, variable, key, "console.ByteSize", ParseByteSize)
+}
+
+func (f *FlagParser) ParseRegexp(variable **regexp.Regexp, key string) {
+	parseValue(f, variable, key, "*regexp.Regexp", regexp.Compile)
+}
+
+// ParseStringMap reads values like "key=value" or "a=1,b=2". All values
+// sent for the flag are merged into one map.
+func (f *FlagParser) ParseStringMap(variable *map[string]string, key string) {
+	if typedVal, ok := defaultValue[map[string]string](f, key, "map[string]string"); ok {
+		*variable = maps.Clone(typedVal)
+		return
 	}
-	for _, v := range vals {
-		i64, err := strconv.Atoi(v)
-		if err != nil {
+	if *variable == nil {
+		*variable = make(map[string]string)
+	}
+	for _, s := range f.flags[key] {
+		if err := parseStringMap(*variable, s); err != nil {
 			panic(err)
 		}
-		*variable = append(*variable, int(i64))
 	}
 }
 
+func (f *FlagParser) ParseIntSlice(variable *[]int, key string) {
+	parseSlice(f, variable, key, "[]int", parseInt)
+}
+
 func (f *FlagParser) ParseInt64Slice(variable *[]int64, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().([]int64)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "[]int64")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
-	}
-	for _, v := range vals {
-		i64, err := strconv.ParseInt(v, 10, 64)
-		if err != nil {
-			panic(err)
-		}
-		*variable = append(*variable, i64)
-	}
+	parseSlice(f, variable, key, "[]int64", parseInt64)
 }
 
 func (f *FlagParser) ParseUintSlice(variable *[]uint, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().([]uint)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "[]uint")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
-	}
-	for _, v := range vals {
-		u64, err := strconv.ParseUint(v, 10, 64)
-		if err != nil {
-			panic(err)
-		}
-		*variable = append(*variable, uint(u64))
-	}
+	parseSlice(f, variable, key, "[]uint", parseUint)
 }
 
 func (f *FlagParser) ParseUint64Slice(variable *[]uint64, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().([]uint64)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "[]uint64")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
-	}
-	for _, v := range vals {
-		u64, err := strconv.ParseUint(v, 10, 64)
-		if err != nil {
-			panic(err)
-		}
-		*variable = append(*variable, u64)
+	parseSlice(f, variable, key, "[]uint64", parseUint64)
+}
+
+func (f *FlagParser) ParseBoolSlice(variable *[]bool, key string) {
+	f.mustBeDeclared(key)
+	if vals, ok := f.flags[key]; ok && len(vals) == 0 {
+		// a bare --flag
+		*variable = append(*variable, true)
+		return
 	}
+	parseSlice(f, variable, key, "[]bool", parseBool)
 }
 
 func (f *FlagParser) ParseStringSlice(variable *[]string, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().([]string)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "[]string")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
-	}
-	*variable = append(*variable, vals...)
+	parseSlice(f, variable, key, "[]string", parseString)
 }
 
 func (f *FlagParser) ParseFloat64Slice(variable *[]float64, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().([]float64)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "[]float64")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
-	}
-	for _, v := range vals {
-		f64, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			panic(err)
-		}
-		*variable = append(*variable, f64)
-	}
+	parseSlice(f, variable, key, "[]float64", parseFloat64)
 }
 
 func (f *FlagParser) ParseDurationSlice(variable *[]time.Duration, key string) {
-	vals := f.flags[key]
-	defValData := f.getFlagValueData(key)
-	if defValData.Get() != nil {
-		typedVal, ok := defValData.Get().([]time.Duration)
-		if !ok {
-			f.writeWarning(key, defValData.Type(), "[]time.Duration")
-		}
-		if len(vals) == 0 {
-			*variable = typedVal
-			return
-		}
-	}
-	for _, v := range vals {
-		d, err := time.ParseDuration(v)
-		if err != nil {
-			panic(err)
-		}
-		*variable = append(*variable, d)
-	}
+	parseSlice(f, variable, key, "[]time.Duration", time.ParseDuration)
 }
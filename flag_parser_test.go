@@ -0,0 +1,57 @@
+package console
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestUndeclaredFlag(t *testing.T) {
+	r, l := newListener()
+	r.RegisterCommand("asd", "", func(in Input, out Output) error {
+		var asd bool
+		in.ParseBool(&asd, "asd")
+		return nil
+	})
+
+	_, exitErr := runCommand(t, l, CommandMessage{Name: "asd"})
+	if exitErr != `command "asd": undeclared flag --asd` {
+		t.Errorf("runCommand() error = %q; want %q", exitErr, `command "asd": undeclared flag --asd`)
+	}
+}
+
+func TestDefaultsAreCopied(t *testing.T) {
+	r, l := newListener()
+	cmd, _ := r.RegisterCommand("asd", "", func(in Input, out Output) error {
+		var (
+			tags   []string
+			labels map[string]string
+			ip     net.IP
+			u      *url.URL
+		)
+		in.ParseStringSlice(&tags, "tag")
+		in.ParseStringMap(&labels, "label")
+		in.ParseIP(&ip, "ip")
+		in.ParseURL(&u, "url")
+		fmt.Fprint(out, tags, labels, ip, u)
+
+		// the callback changes its values, not the defaults of later runs
+		tags[0] = "changed"
+		labels["a"] = "changed"
+		ip[0] = 0
+		u.Host = "changed"
+		return nil
+	})
+	cmd.OptionalSliceString("tag", "", []string{"a"}).
+		OptionalStringMap("label", "", map[string]string{"a": "1"}).
+		OptionalIP("ip", "", net.IPv4(10, 0, 0, 1).To4()).
+		OptionalURL("url", "", &url.URL{Scheme: "https", Host: "example.com"})
+
+	const expected = "[a] map[a:1] 10.0.0.1 https://example.com"
+	for i := 0; i < 2; i++ {
+		if out, exitErr := runCommand(t, l, CommandMessage{Name: "asd"}); out != expected || exitErr != "" {
+			t.Errorf("run %d = %q, %q; want %q", i, out, exitErr, expected)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ByteSize is a number of bytes written with an optional decimal (kB, MB,
+// GB, TB) or binary (KiB, MiB, GiB, TiB) unit, e.g. "512MiB" or "1.5GB".
+type ByteSize uint64
+
+const (
+	Byte ByteSize = 1
+
+	KB ByteSize = 1000 * Byte
+	MB ByteSize = 1000 * KB
+	GB ByteSize = 1000 * MB
+	TB ByteSize = 1000 * GB
+
+	KiB ByteSize = 1024 * Byte
+	MiB ByteSize = 1024 * KiB
+	GiB ByteSize = 1024 * MiB
+	TiB ByteSize = 1024 * GiB
+)
+
+var byteSizeUnits = map[string]ByteSize{
+	"":    Byte,
+	"b":   Byte,
+	"k":   KiB,
+	"kb":  KB,
+	"kib": KiB,
+	"m":   MiB,
+	"mb":  MB,
+	"mib": MiB,
+	"g":   GiB,
+	"gb":  GB,
+	"gib": GiB,
+	"t":   TiB,
+	"tb":  TB,
+	"tib": TiB,
+}
+
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	unit, ok := byteSizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size %q: unknown unit %q", s, s[i:])
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	size := n * float64(unit)
+	if size > math.MaxUint64 {
+		return 0, fmt.Errorf("byte size %q out of range", s)
+	}
+	return ByteSize(size), nil
+}
+
+func (b ByteSize) String() string {
+	for _, unit := range []struct {
+		size ByteSize
+		name string
+	}{{TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"}} {
+		if b >= unit.size && b%unit.size == 0 {
+			return fmt.Sprintf("%d%s", b/unit.size, unit.name)
+		}
+	}
+	return fmt.Sprintf("%dB", uint64(b))
+}
+
+func parseInt(s string) (int, error) {
+	return strconv.Atoi(s)
+}
+
+func parseInt8(s string) (int8, error) {
+	i, err := strconv.ParseInt(s, 10, 8)
+	return int8(i), err
+}
+
+func parseInt16(s string) (int16, error) {
+	i, err := strconv.ParseInt(s, 10, 16)
+	return int16(i), err
+}
+
+func parseInt32(s string) (int32, error) {
+	i, err := strconv.ParseInt(s, 10, 32)
+	return int32(i), err
+}
+
+func parseInt64(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func parseUint(s string) (uint, error) {
+	u, err := strconv.ParseUint(s, 10, 64)
+	return uint(u), err
+}
+
+func parseUint64(s string) (uint64, error) {
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func parseBool(s string) (bool, error) {
+	return strconv.ParseBool(s)
+}
+
+func parseString(s string) (string, error) {
+	return s, nil
+}
+
+func parseFloat32(s string) (float32, error) {
+	f, err := strconv.ParseFloat(s, 32)
+	return float32(f), err
+}
+
+func parseFloat64(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseTime reads RFC 3339 timestamps, "now" and times relative to now
+// like "-2h" or "+30m".
+func parseTime(s string) (time.Time, error) {
+	if s == "now" {
+		return time.Now(), nil
+	}
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time %q: %w", s, err)
+		}
+		return time.Now().Add(d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func parseIP(s string) (net.IP, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", s)
+	}
+	return ip, nil
+}
+
+func parseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("invalid URL %q: missing scheme", s)
+	}
+	return u, nil
+}
+
+func parseStringMap(m map[string]string, s string) error {
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return errors.New("invalid key=value pair " + strconv.Quote(pair))
+		}
+		m[key] = value
+	}
+	return nil
+}
+
+func anyParser[T any](parse func(string) (T, error)) func(string) (any, error) {
+	return func(s string) (any, error) {
+		return parse(s)
+	}
+}
+
+// valueParsers parse a single value of each declared flag type, slices are
+// parsed per element.
+var valueParsers = map[string]func(string) (any, error){
+	"int":              anyParser(parseInt),
+	"int8":             anyParser(parseInt8),
+	"int16":            anyParser(parseInt16),
+	"int32":            anyParser(parseInt32),
+	"int64":            anyParser(parseInt64),
+	"uint":             anyParser(parseUint),
+	"uint64":           anyParser(parseUint64),
+	"bool":             anyParser(parseBool),
+	"string":           anyParser(parseString),
+	"float32":          anyParser(parseFloat32),
+	"float64":          anyParser(parseFloat64),
+	"time.Duration":    anyParser(time.ParseDuration),
+	"time.Time":        anyParser(parseTime),
+	"net.IP":           anyParser(parseIP),
+	"netip.Prefix":     anyParser(netip.ParsePrefix),
+	"*url.URL":         anyParser(parseURL),
+	"console.ByteSize": anyParser(ParseByteSize),
+	"*regexp.Regexp":   anyParser(regexp.Compile),
+	"map[string]string": func(s string) (any, error) {
+		m := make(map[string]string)
+		return m, parseStringMap(m, s)
+	},
+}
+
+func valueParser(dataType string) (func(string) (any, error), bool) {
+	parse, ok := valueParsers[strings.TrimPrefix(dataType, "[]")]
+	return parse, ok
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case ByteSize:
+		return float64(v), true
+	case time.Duration:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,102 @@
+package console
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected ByteSize
+	}{
+		{"512", 512},
+		{"512B", 512},
+		{"512MiB", 512 * MiB},
+		{"1.5GB", 1500 * MB},
+		{"2k", 2 * KiB},
+		{"1 TiB", TiB},
+	}
+	for _, test := range tests {
+		b, err := ParseByteSize(test.s)
+		if err != nil || b != test.expected {
+			t.Errorf("ParseByteSize(%q) = %v, %v; want %v", test.s, b, err, test.expected)
+		}
+	}
+
+	for _, s := range []string{"", "MiB", "12XB", "1.2.3KB"} {
+		if _, err := ParseByteSize(s); err == nil {
+			t.Errorf("ParseByteSize(%q) error = nil; want error", s)
+		}
+	}
+
+	if s := (512 * MiB).String(); s != "512MiB" {
+		t.Errorf("ByteSize.String() = %q; want %q", s, "512MiB")
+	}
+	if s := ByteSize(1500).String(); s != "1500B" {
+		t.Errorf("ByteSize.String() = %q; want %q", s, "1500B")
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	before := time.Now()
+	tm, err := parseTime("-2h")
+	if err != nil || tm.After(before.Add(-2*time.Hour+time.Minute)) || tm.Before(before.Add(-2*time.Hour-time.Minute)) {
+		t.Errorf("parseTime(\"-2h\") = %v, %v; want about %v", tm, err, before.Add(-2*time.Hour))
+	}
+
+	tm, err = parseTime("2023-01-02T15:04:05Z")
+	if expected := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC); err != nil || !tm.Equal(expected) {
+		t.Errorf("parseTime(RFC3339) = %v, %v; want %v", tm, err, expected)
+	}
+}
+
+func TestFlagParserExtendedTypes(t *testing.T) {
+	f := &FlagParser{
+		flags: map[string][]string{
+			"asd":  {"-100"},
+			"asd2": {"10.0.0.0/8"},
+			"asd3": {"a=1,b=2", "c=3"},
+			"asd4": {"300"},
+		},
+		flagsInfo: map[string]commonFlagInfo{
+			"asd":  {valueData: typeOnlyValueData{dataType: "int8"}},
+			"asd2": {valueData: typeOnlyValueData{dataType: "netip.Prefix"}},
+			"asd3": {valueData: typeOnlyValueData{dataType: "map[string]string"}},
+			"asd4": {valueData: typeOnlyValueData{dataType: "int8"}},
+			"asd5": {valueData: fullValueData{dataType: "console.ByteSize", dataValue: 2 * MiB}},
+		},
+	}
+
+	var i8 int8
+	f.ParseInt8(&i8, "asd")
+	if i8 != -100 {
+		t.Errorf("ParseInt8() = %v; want %v", i8, -100)
+	}
+
+	var prefix netip.Prefix
+	f.ParsePrefix(&prefix, "asd2")
+	if prefix != netip.MustParsePrefix("10.0.0.0/8") {
+		t.Errorf("ParsePrefix() = %v; want %v", prefix, "10.0.0.0/8")
+	}
+
+	var m map[string]string
+	f.ParseStringMap(&m, "asd3")
+	if len(m) != 3 || m["a"] != "1" || m["b"] != "2" || m["c"] != "3" {
+		t.Errorf("ParseStringMap() = %v; want map[a:1 b:2 c:3]", m)
+	}
+
+	var size ByteSize
+	f.ParseByteSize(&size, "asd5")
+	if size != 2*MiB {
+		t.Errorf("ParseByteSize() = %v; want %v", size, 2*MiB)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("ParseInt8() out of range did not panic")
+		}
+	}()
+	f.ParseInt8(&i8, "asd4")
+}
@@ -0,0 +1,110 @@
+package console
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// FlagValue lets commands declare flags of their own types, like
+// flag.Value. Set is called for every value sent for the flag, Type names
+// the type in help output.
+type FlagValue interface {
+	String() string
+	Set(string) error
+	Type() string
+}
+
+// FlagValueValidator is implemented by values checked after Set, before
+// the command runs.
+type FlagValueValidator interface {
+	Validate() error
+}
+
+// FlagValueCompleter is implemented by values which suggest candidates for
+// shell completion.
+type FlagValueCompleter interface {
+	Complete(prefix string) []string
+}
+
+// customValueData describes a flag declared with a FlagValue. The value
+// given at declaration is never set, it serves as the default of optional
+// flags and as the prototype of values created for validation.
+type customValueData struct {
+	value    FlagValue
+	required bool
+}
+
+func newCustomValueData(value FlagValue, required bool) customValueData {
+	if reflect.ValueOf(value).Kind() != reflect.Pointer {
+		panic(fmt.Sprintf("console: FlagValue of type %T must be a pointer", value))
+	}
+	return customValueData{value: value, required: required}
+}
+
+func (v customValueData) String() string {
+	return fmt.Sprintf("type: %s", v.value.Type())
+}
+
+func (v customValueData) Type() string {
+	return v.value.Type()
+}
+
+func (v customValueData) Get() any {
+	if v.required {
+		return nil
+	}
+	return v.value
+}
+
+func (v customValueData) newValue() FlagValue {
+	return reflect.New(reflect.TypeOf(v.value).Elem()).Interface().(FlagValue)
+}
+
+func (v customValueData) parse(s string) (any, error) {
+	value := v.newValue()
+	if err := value.Set(s); err != nil {
+		return nil, err
+	}
+	if validator, ok := value.(FlagValueValidator); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+func (v customValueData) complete(prefix string) []string {
+	if completer, ok := v.value.(FlagValueCompleter); ok {
+		return completer.Complete(prefix)
+	}
+	return nil
+}
+
+// RequiredVar declares a flag parsed by value, which is used as a
+// prototype only, use Input.ParseVar to read the flag.
+func (c *commonCommandInfo) RequiredVar(name, description string, value FlagValue) Command {
+	return c.requiredFlagInfo(name, description, newCustomValueData(value, true))
+}
+
+// OptionalVar declares a flag parsed by value, whose current String is
+// the default of the flag.
+func (c *commonCommandInfo) OptionalVar(name, description string, value FlagValue) Command {
+	return c.optionalFlagInfo(name, description, newCustomValueData(value, false))
+}
+
+// ParseVar calls variable.Set for every value of the flag, or with the
+// default of an optional flag which was not sent.
+func (f *FlagParser) ParseVar(variable FlagValue, key string) {
+	data := f.getFlagValueData(key)
+	vals := f.flags[key]
+	if len(vals) == 0 {
+		if def, ok := data.Get().(FlagValue); ok {
+			vals = []string{def.String()}
+		}
+	}
+	for _, v := range vals {
+		if err := variable.Set(v); err != nil {
+			panic(err)
+		}
+	}
+}
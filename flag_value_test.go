@@ -0,0 +1,68 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type tenantID string
+
+func (t *tenantID) String() string { return string(*t) }
+func (t *tenantID) Type() string   { return "tenant" }
+
+func (t *tenantID) Set(s string) error {
+	if !strings.HasPrefix(s, "t-") {
+		return fmt.Errorf("tenant %q must start with t-", s)
+	}
+	*t = tenantID(s)
+	return nil
+}
+
+func (t *tenantID) Validate() error {
+	if len(*t) > 8 {
+		return fmt.Errorf("tenant %q is too long", string(*t))
+	}
+	return nil
+}
+
+func (t *tenantID) Complete(prefix string) []string {
+	return []string{"t-acme", "t-demo"}
+}
+
+func TestCustomFlags(t *testing.T) {
+	r, l := newListener()
+
+	cmd, _ := r.RegisterCommand("asd", "", func(in Input, out Output) error {
+		var tenant tenantID
+		in.ParseVar(&tenant, "tenant")
+		fmt.Fprint(out, tenant)
+		return nil
+	})
+	def := tenantID("t-acme")
+	cmd.OptionalVar("tenant", "Tenant", &def)
+
+	if out, _ := runCommand(t, l, CommandMessage{Name: "asd"}); out != "t-acme" {
+		t.Errorf("runCommand(\"asd\") = %q; want %q", out, "t-acme")
+	}
+	if out, _ := runCommand(t, l, CommandMessage{Name: "asd", Flags: map[string][]string{"tenant": {"t-demo"}}}); out != "t-demo" {
+		t.Errorf("runCommand(\"asd --tenant t-demo\") = %q; want %q", out, "t-demo")
+	}
+	_, exitErr := runCommand(t, l, CommandMessage{Name: "asd", Flags: map[string][]string{"tenant": {"acme"}}})
+	if expected := `invalid value "acme" for flag --tenant: tenant "acme" must start with t-`; exitErr != expected {
+		t.Errorf("runCommand(\"asd --tenant acme\") error = %q; want %q", exitErr, expected)
+	}
+	_, exitErr = runCommand(t, l, CommandMessage{Name: "asd", Flags: map[string][]string{"tenant": {"t-toolong"}}})
+	if expected := `invalid value "t-toolong" for flag --tenant: tenant "t-toolong" is too long`; exitErr != expected {
+		t.Errorf("runCommand(\"asd --tenant t-toolong\") error = %q; want %q", exitErr, expected)
+	}
+	if def != "t-acme" {
+		t.Errorf("default value changed to %q", def)
+	}
+	if out, _ := runCommand(t, l, CommandMessage{Name: "asd", Flags: map[string][]string{"help": nil}}); !strings.Contains(out, "--tenant tenant") {
+		t.Errorf("runCommand(\"asd --help\") = %q; want type of --tenant", out)
+	}
+	if got, expected := r.complete([]string{"asd", "--tenant", "t-d"}), []string{"t-demo"}; !stringsEqual(got, expected) {
+		t.Errorf("complete() = %q; want %q", got, expected)
+	}
+}
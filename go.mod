@@ -1,3 +1,3 @@
 module github.com/Kankeran/console
 
-go 1.20
+go 1.21